@@ -0,0 +1,60 @@
+package consulparser
+
+import (
+	"fmt"
+	"reflect"
+)
+
+//RegisterConverter registers the function converting the raw values of the fields of the type,
+//e.g. for types of other modules that can't implement ConsulKVUnmarshaler.
+//Converters take precedence over the unmarshalers and the kind switch, and the fields pointing to the type
+//are converted as well. Struct types with a converter are assigned from their own key instead of being walked.
+//The converted value must be assignable to the type, otherwise the field fails with ErrTypeMismatch.
+//RegisterConverter must not be called concurrently with Parse, prefer WithConverter in NewParser.
+func (parser *Parser) RegisterConverter(typ reflect.Type, converter func(string) (interface{}, error)) (err error) {
+	if typ == nil || converter == nil {
+		err = ErrNilConverter
+		return
+	}
+	if parser.converters == nil {
+		parser.converters = make(map[reflect.Type]func(string) (interface{}, error))
+	}
+	parser.converters[typ] = converter
+	return
+}
+
+//convert converts the value with the converter registered for the type of val or the type it points to.
+//handled is false when there is no converter, so the value goes through the unmarshalers and the kind switch.
+//Empty values leave the field untouched.
+func (parser *Parser) convert(val reflect.Value, value string) (handled bool, err error) {
+	typ := val.Type()
+	converter, ok := parser.converters[typ]
+	if !ok && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+		converter, ok = parser.converters[typ]
+	}
+	if !ok {
+		return
+	}
+	handled = true
+	if value == "" {
+		return
+	}
+	converted, err := converter(value)
+	if err != nil {
+		return
+	}
+	convertedVal := reflect.ValueOf(converted)
+	if !convertedVal.IsValid() || !convertedVal.Type().AssignableTo(typ) {
+		err = fmt.Errorf("%w: %T is not %s", ErrTypeMismatch, converted, typ)
+		return
+	}
+	newVal := reflect.New(typ)
+	newVal.Elem().Set(convertedVal)
+	if typ != val.Type() {
+		val.Set(newVal)
+		return
+	}
+	val.Set(newVal.Elem())
+	return
+}
@@ -0,0 +1,114 @@
+package consulparser
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errInvalidMoney = errors.New("invalid money amount")
+
+//money and semVer stand for types of other modules, so they can't implement ConsulKVUnmarshaler.
+type money struct {
+	Currency string
+	Cents    int64
+}
+
+type semVer struct {
+	Major, Minor, Patch int
+}
+
+func TestParser_RegisterConverter(t *testing.T) {
+	type target struct {
+		Price    money        `consulkv:"price"`
+		Discount *money       `consulkv:"discount"`
+		Prices   []money      `consulkv:"prices"`
+		Version  **semVer     `consulkv:"version"`
+		Networks []*net.IPNet `consulkv:"networks"`
+	}
+	converters := map[reflect.Type]func(string) (interface{}, error){
+		reflect.TypeOf(money{}): func(value string) (interface{}, error) {
+			var amount money
+			var units, cents int64
+			_, err := fmt.Sscanf(value, "%s %d.%d", &amount.Currency, &units, &cents)
+			if err != nil {
+				return nil, errInvalidMoney
+			}
+			amount.Cents = units*100 + cents
+			return amount, nil
+		},
+		reflect.TypeOf(semVer{}): func(value string) (interface{}, error) {
+			var ver semVer
+			_, err := fmt.Sscanf(value, "%d.%d.%d", &ver.Major, &ver.Minor, &ver.Patch)
+			return ver, err
+		},
+		reflect.TypeOf([]*net.IPNet{}): func(value string) (interface{}, error) {
+			var networks []*net.IPNet
+			for _, cidr := range strings.Split(value, ",") {
+				_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+				if err != nil {
+					return nil, err
+				}
+				networks = append(networks, network)
+			}
+			return networks, nil
+		},
+	}
+	values := map[string]string{
+		"price":    "EUR 12.50",
+		"discount": "EUR 1.05",
+		"prices":   "USD 1.00,USD 2.25",
+		"version":  "1.4.2",
+		"networks": "10.0.0.0/8, 192.168.0.0/16",
+	}
+	source := NewMemorySource(values)
+	_, err := NewParserWithSource(source, WithConverter(nil, converters[reflect.TypeOf(money{})]))
+	assert.ErrorIs(t, err, ErrNilConverter)
+	_, err = NewParserWithSource(source, WithConverter(reflect.TypeOf(money{}), nil))
+	assert.ErrorIs(t, err, ErrNilConverter)
+	var opts []Option
+	for typ, converter := range converters {
+		opts = append(opts, WithConverter(typ, converter))
+	}
+	parser, err := NewParserWithSource(source, opts...)
+	assert.NoError(t, err)
+	//Structs with a converter are assigned from their own key instead of being walked or read from subfolders.
+	assert.Equal(t, []string{"price", "discount", "prices", "version", "networks"}, parser.(*Parser).targetKeys(reflect.ValueOf(&target{}).Elem()))
+	//Nil targets fail before any converter is reached.
+	assert.ErrorIs(t, parser.Parse((*target)(nil)), ErrNonPointerType)
+	var nilTarget *target
+	assert.ErrorIs(t, parser.Parse(&nilTarget), ErrNonPointerType)
+
+	result := &target{}
+	assert.NoError(t, parser.Parse(result))
+	version := &semVer{Major: 1, Minor: 4, Patch: 2}
+	_, first, _ := net.ParseCIDR("10.0.0.0/8")
+	_, second, _ := net.ParseCIDR("192.168.0.0/16")
+	assert.Equal(t, &target{
+		Price:    money{Currency: "EUR", Cents: 1250},
+		Discount: &money{Currency: "EUR", Cents: 105},
+		Prices:   []money{{Currency: "USD", Cents: 100}, {Currency: "USD", Cents: 225}},
+		Version:  &version,
+		Networks: []*net.IPNet{first, second},
+	}, result)
+
+	//Errors of the converters are reported on the field.
+	source.Set("discount", "1.05")
+	err = parser.Parse(result)
+	assert.ErrorIs(t, err, errInvalidMoney)
+	var fieldErr *FieldError
+	assert.True(t, errors.As(err, &fieldErr))
+	assert.Equal(t, "Discount", fieldErr.Path)
+	source.Set("discount", "EUR 1.05")
+
+	//The converted value must be assignable to the type.
+	assert.NoError(t, parser.RegisterConverter(reflect.TypeOf(semVer{}), func(value string) (interface{}, error) {
+		return value, nil
+	}))
+	assert.ErrorIs(t, parser.Parse(result), ErrTypeMismatch)
+}
@@ -0,0 +1,39 @@
+package consulparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/hashicorp/hcl"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatHCL  = "hcl"
+)
+
+//decoders defines the decoders of the formats that can be set in the tag, indexed by their tag option.
+var decoders = map[string]func(data []byte, target interface{}) error{
+	formatJSON: json.Unmarshal,
+	formatYAML: yaml.Unmarshal,
+	formatHCL:  hcl.Unmarshal,
+}
+
+//decode decodes the whole value into val with the decoder of the format instead of the kind switch,
+//so structs, maps and slices are filled from a single key. Empty values leave the field untouched.
+func decode(val reflect.Value, value, format string) (err error) {
+	if value == "" {
+		return
+	}
+	newVal := reflect.New(val.Type())
+	err = decoders[format]([]byte(value), newVal.Interface())
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrInvalidDocument, err)
+		return
+	}
+	val.Set(newVal.Elem())
+	return
+}
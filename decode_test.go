@@ -0,0 +1,91 @@
+package consulparser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParser_Parse_Format(t *testing.T) {
+	type route struct {
+		Path    string `json:"path" yaml:"path" hcl:"path"`
+		Backend string `json:"backend" yaml:"backend" hcl:"backend"`
+	}
+	type routing struct {
+		Routes []route `json:"routes" yaml:"routes" hcl:"routes"`
+	}
+	type pool struct {
+		Hosts []string `hcl:"hosts"`
+		Size  int      `hcl:"size"`
+	}
+	type target struct {
+		Routing routing           `consulkv:"routing,json"`
+		Limits  map[string]int    `consulkv:"limits,yaml"`
+		Pool    *pool             `consulkv:"pool,hcl"`
+		Labels  map[string]string `consulkv:"labels,json,default={\"tier\": \"web\"}"`
+	}
+	tests := []struct {
+		name       string
+		values     map[string]string
+		wantResult *target
+		wantErr    error
+	}{
+		{
+			name: "Decoded Documents",
+			values: map[string]string{
+				"routing": `{"routes": [{"path": "/api", "backend": "api"}, {"path": "/", "backend": "web"}]}`,
+				"limits":  "tenantA: 100\ntenantB: 200\n",
+				"pool":    "hosts = [\"api\", \"web\"]\nsize = 2\n",
+			},
+			wantResult: &target{
+				Routing: routing{
+					Routes: []route{
+						{Path: "/api", Backend: "api"},
+						{Path: "/", Backend: "web"},
+					},
+				},
+				Limits: map[string]int{
+					"tenantA": 100,
+					"tenantB": 200,
+				},
+				Pool: &pool{
+					Hosts: []string{"api", "web"},
+					Size:  2,
+				},
+				Labels: map[string]string{
+					"tier": "web",
+				},
+			},
+		},
+		{
+			name: "Invalid Document",
+			values: map[string]string{
+				"routing": `{"routes": [`,
+			},
+			wantErr: ErrInvalidDocument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParserWithSource(NewMemorySource(tt.values), WithMissingKeyPolicy(MissingKeySkip))
+			assert.NoError(t, err)
+			result := &target{}
+			err = parser.Parse(result)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				var fieldErr *FieldError
+				assert.True(t, errors.As(err, &fieldErr))
+				assert.Equal(t, "routing", fieldErr.Key)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantResult, result)
+		})
+	}
+	parser, err := NewParserWithSource(NewMemorySource(nil))
+	assert.NoError(t, err)
+	//Decoded fields are read from their own key instead of being walked or read from a folder.
+	assert.Equal(t, []string{"routing", "limits", "pool", "labels"}, parser.(*Parser).targetKeys(reflect.ValueOf(&target{}).Elem()))
+}
@@ -0,0 +1,83 @@
+package consulparser
+
+import (
+	"reflect"
+)
+
+//Change defines a field whose value differs between two parsed values of the same type.
+type Change struct {
+	//Path is the Go field path, e.g. Database.Primary.Port.
+	Path string
+	//Key is the consul key of the field.
+	Key string
+	//Old is the value of the field before the reload.
+	Old interface{}
+	//New is the value of the field after the reload.
+	New interface{}
+}
+
+//Diff compares the fields of two values of the same type, walking them the same way Parse does.
+//Both values may be given as pointers, which are dereferenced like the target of Parse.
+//Struct fields are compared field by field, a nil struct pointer being compared as an empty struct,
+//while every other field is compared as a whole with reflect.DeepEqual.
+func (parser *Parser) Diff(oldValue, newValue interface{}) (changes []Change, err error) {
+	oldVal := parser.getRecursivePointerVal(reflect.ValueOf(oldValue))
+	newVal := parser.getRecursivePointerVal(reflect.ValueOf(newValue))
+	if !oldVal.IsValid() || !newVal.IsValid() || oldVal.Type() != newVal.Type() {
+		err = ErrTypeMismatch
+		return
+	}
+	if !parser.isNestedStruct(oldVal.Type()) {
+		if !reflect.DeepEqual(oldVal.Interface(), newVal.Interface()) {
+			changes = append(changes, Change{
+				Old: oldVal.Interface(),
+				New: newVal.Interface(),
+			})
+		}
+		return
+	}
+	changes = parser.diff(oldVal, newVal, scope{}, changes)
+	return
+}
+
+func (parser *Parser) diff(oldVal, newVal reflect.Value, sc scope, changes []Change) []Change {
+	typ := oldVal.Type()
+	for index := 0; index < typ.NumField(); index++ {
+		field := typ.Field(index)
+		//Unexported fields are never set by parse.
+		if field.PkgPath != "" {
+			continue
+		}
+		tagOpts := parseTag(field.Tag.Get(parser.tag()))
+		fieldScope := sc.field(field.Name)
+		oldField, newField := oldVal.Field(index), newVal.Field(index)
+		if tagOpts.format == "" && parser.isNestedStruct(indirectType(field.Type)) {
+			fieldScope.prefix = resolvePrefix(sc.prefix, tagOpts.key)
+			changes = parser.diff(indirectStruct(oldField), indirectStruct(newField), fieldScope, changes)
+			continue
+		}
+		if reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+			continue
+		}
+		//A key with a broken template is reported as written in the tag.
+		consulKey, _ := parser.expandKey(parser.resolveFieldKey(sc.prefix, tagOpts, field.Type))
+		changes = append(changes, Change{
+			Path: fieldScope.path,
+			Key:  consulKey,
+			Old:  oldField.Interface(),
+			New:  newField.Interface(),
+		})
+	}
+	return changes
+}
+
+//indirectStruct dereferences the pointers of a struct field, using an empty struct for nil pointers.
+func indirectStruct(val reflect.Value) reflect.Value {
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return reflect.Zero(indirectType(val.Type()))
+		}
+		val = val.Elem()
+	}
+	return val
+}
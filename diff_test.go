@@ -0,0 +1,122 @@
+package consulparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParser_Diff(t *testing.T) {
+	type DBConfig struct {
+		Host string `consulkv:"host"`
+		Port *int   `consulkv:"port"`
+	}
+	type config struct {
+		Name     string    `consulkv:"name"`
+		Primary  DBConfig  `consulkv:"db/primary/"`
+		Replica  *DBConfig `consulkv:"db/replica/"`
+		Timeout  int       `consulkv:"/global/timeout"`
+		internal string
+	}
+	port, otherPort := 5432, 5433
+	tests := []struct {
+		name        string
+		rootPrefix  string
+		oldValue    interface{}
+		newValue    interface{}
+		wantChanges []Change
+		wantErr     error
+	}{
+		{
+			name: "Pointer Fields Compared by Value",
+			oldValue: &config{
+				Name: "app",
+				Primary: DBConfig{
+					Port: &port,
+				},
+			},
+			newValue: &config{
+				Name: "app",
+				Primary: DBConfig{
+					Port: &otherPort,
+				},
+			},
+			wantChanges: []Change{
+				{
+					Path: "Primary.Port",
+					Key:  "db/primary/port",
+					Old:  &port,
+					New:  &otherPort,
+				},
+			},
+		},
+		{
+			name:       "Changed Fields in Nested Structs",
+			rootPrefix: "production/",
+			oldValue: &config{
+				Name:     "app",
+				internal: "old",
+			},
+			newValue: &config{
+				Name: "app",
+				Primary: DBConfig{
+					Host: "primary.local",
+				},
+				Replica: &DBConfig{
+					Host: "replica.local",
+				},
+				Timeout:  30,
+				internal: "new",
+			},
+			wantChanges: []Change{
+				{
+					Path: "Primary.Host",
+					Key:  "production/db/primary/host",
+					Old:  "",
+					New:  "primary.local",
+				},
+				{
+					Path: "Replica.Host",
+					Key:  "production/db/replica/host",
+					Old:  "",
+					New:  "replica.local",
+				},
+				{
+					Path: "Timeout",
+					Key:  "production/global/timeout",
+					Old:  0,
+					New:  30,
+				},
+			},
+		},
+		{
+			name:     "Non Struct Values",
+			oldValue: "old",
+			newValue: "new",
+			wantChanges: []Change{
+				{
+					Old: "old",
+					New: "new",
+				},
+			},
+		},
+		{
+			name:     "Type Mismatch",
+			oldValue: &config{},
+			newValue: &DBConfig{},
+			wantErr:  ErrTypeMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := &Parser{
+				rootPrefix: tt.rootPrefix,
+			}
+			changes, err := parser.Diff(tt.oldValue, tt.newValue)
+			if err != tt.wantErr {
+				t.Errorf("Parser.Diff() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.wantChanges, changes)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+//Package consulparser parses the values of consul keys into the fields of a struct, named by their tag.
+//
+//The tag of a struct field is the prefix of the keys of its children, e.g. `consulkv:"database/"`,
+//so the same struct type can be reused under different prefixes.
+//Keys starting with a slash are absolute and ignore the prefix of their parents,
+//but every key is still put under the root prefix of the parser.
+//Keys may use the variables of the parser as templates, e.g. `consulkv:"{{.Env}}/db/host"`.
+//
+//The key may be followed by options, e.g. `consulkv:"db/port,required"` or `consulkv:"db/port,default=5432"`.
+//A required key fails the parse when it doesn't exist in consul, whatever the missing key policy is.
+//A default literal is converted like a consul value and used when the key doesn't exist;
+//it must be the last option, since everything after "default=" belongs to the literal.
+//
+//The value of a field is taken from the environment first when the parser is built with WithEnvOverrides,
+//then from the layers of the parser, then from the default option, before applying the missing key policy.
+//NewEnvSource puts the environment in a layer instead, e.g. below the flags but above consul.
+//
+//Types with a converter registered by RegisterConverter are converted by it first.
+//Types implementing ConsulKVUnmarshaler or encoding.TextUnmarshaler, e.g. net.IP, decode their own value,
+//except time.Time which is parsed with the time layout of the parser.
+//Slices and arrays are read from a JSON array or from a value delimited by the separator option,
+//e.g. `consulkv:"kafka/brokers,sep=;"`, which is a comma by default.
+//Maps with string keys are filled with the direct children of the folder named by their tag,
+//e.g. `consulkv:"limits/"` reads limits/tenantA into the tenantA entry.
+//Maps of structs are filled with a struct parsed from every subfolder, e.g. upstreams/primary/host,
+//and slices of structs likewise from the subfolders named by their index, e.g. upstreams/0/host.
+//The json, yaml or hcl option decodes a whole document stored in a single key into the field instead,
+//e.g. `consulkv:"routing,json"`.
+package consulparser
@@ -0,0 +1,96 @@
+package consulparser
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+//envTagName is the struct tag holding the environment variable that overrides the consul key of a field.
+const envTagName = "env"
+
+//lookupEnv returns the value of the environment variable overriding the consul key of a field.
+//The variable is the one named by the env tag, e.g. `env:"DB_HOST"`,
+//or the consul key relative to the root prefix mapped to an environment variable name after the prefix.
+//Without a prefix only the env tags are honoured, so keys aren't mapped to bare names like PATH.
+//Variables that are unset or empty don't override anything.
+func (parser *Parser) lookupEnv(envTag, consulKey string) (value string, found bool) {
+	if !parser.envOverrides {
+		return
+	}
+	name := envTag
+	if name == "" {
+		if consulKey == "" || parser.envPrefix == "" {
+			return
+		}
+		name = parser.envPrefix + envName(strings.TrimPrefix(consulKey, parser.rootPrefix))
+	}
+	value = os.Getenv(name)
+	found = value != ""
+	return
+}
+
+//envSource defines the Source reading the keys from the environment variables named after them.
+type envSource struct {
+	prefix     string
+	rootPrefix string
+}
+
+//NewEnvSource returns the Source reading every key from the environment variable named after it,
+//like WithEnvOverrides, so the environment can be put anywhere in the layers of NewParserWithLayers.
+//The key relative to the root prefix is mapped after the prefix, e.g. APP_DB_HOST for production/db/host
+//with the root prefix "production/". The prefix must not be empty and gets an underscore appended
+//when it doesn't end with one, like the root prefix gets a slash.
+//The source can't list folders, since variable names can't be mapped back to their keys,
+//so maps and folders of structs never take their children from it.
+func NewEnvSource(prefix, rootPrefix string) (source Source, err error) {
+	if prefix == "" {
+		err = ErrEmptyEnvPrefix
+		return
+	}
+	if !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	if rootPrefix != "" && !strings.HasSuffix(rootPrefix, "/") {
+		rootPrefix += "/"
+	}
+	source = &envSource{
+		prefix:     prefix,
+		rootPrefix: rootPrefix,
+	}
+	return
+}
+
+//Get returns the value of the variable named after the key, or nil when it is unset or empty.
+func (source *envSource) Get(ctx context.Context, key string) (pair *api.KVPair, err error) {
+	value := os.Getenv(source.prefix + envName(strings.TrimPrefix(key, source.rootPrefix)))
+	if value == "" {
+		return
+	}
+	pair = &api.KVPair{
+		Key:   key,
+		Value: []byte(value),
+	}
+	return
+}
+
+//List returns no pair, since the keys under the prefix can't be found from the variable names.
+func (source *envSource) List(ctx context.Context, prefix string) (pairs api.KVPairs, err error) {
+	return
+}
+
+//envName maps the consul key to an environment variable name, e.g. db/primary-host to DB_PRIMARY_HOST.
+func envName(key string) string {
+	return strings.Map(func(char rune) rune {
+		switch {
+		case char >= 'A' && char <= 'Z', char >= '0' && char <= '9', char == '_':
+			return char
+		case char >= 'a' && char <= 'z':
+			return char - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, key)
+}
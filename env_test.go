@@ -0,0 +1,223 @@
+package consulparser
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_envName(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "db/host", want: "DB_HOST"},
+		{key: "db/primary-host", want: "DB_PRIMARY_HOST"},
+		{key: "Kafka.Brokers_2", want: "KAFKA_BROKERS_2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.want, envName(tt.key))
+		})
+	}
+}
+
+func TestParser_Parse_EnvOverrides(t *testing.T) {
+	type DBConfig struct {
+		Host     string `consulkv:"host"`
+		Port     int    `consulkv:"port"`
+		Password string `consulkv:"password,required" env:"DB_PASSWORD"`
+	}
+	type target struct {
+		Name string   `consulkv:"name"`
+		DB   DBConfig `consulkv:"db/"`
+	}
+	source := NewMemorySource(map[string]string{
+		"production/name":    "billing",
+		"production/db/host": "localhost",
+		"production/db/port": "5432",
+	})
+	tests := []struct {
+		name       string
+		opts       []Option
+		env        map[string]string
+		wantResult *target
+		wantErr    error
+	}{
+		{
+			name: "Tagged and Mapped Variables",
+			opts: []Option{WithRootPrefix("production"), WithEnvOverrides("APP")},
+			env: map[string]string{
+				"APP_DB_HOST": "db.local",
+				"DB_PASSWORD": "secret",
+				"APP_NAME":    "",
+			},
+			wantResult: &target{
+				Name: "billing",
+				DB: DBConfig{
+					Host:     "db.local",
+					Port:     5432,
+					Password: "secret",
+				},
+			},
+		},
+		{
+			name: "Disabled Overrides",
+			opts: []Option{WithRootPrefix("production")},
+			env: map[string]string{
+				"DB_PASSWORD": "secret",
+			},
+			wantErr: ErrKeyNotFound,
+		},
+		{
+			name: "Empty Prefix Honours Only Tags",
+			opts: []Option{WithRootPrefix("production"), WithEnvOverrides("")},
+			env: map[string]string{
+				"DB_HOST":     "db.local",
+				"NAME":        "shell",
+				"DB_PASSWORD": "secret",
+			},
+			wantResult: &target{
+				Name: "billing",
+				DB: DBConfig{
+					Host:     "localhost",
+					Port:     5432,
+					Password: "secret",
+				},
+			},
+		},
+		{
+			name: "Invalid Variable",
+			opts: []Option{WithRootPrefix("production"), WithEnvOverrides("APP_")},
+			env: map[string]string{
+				"APP_DB_PORT": "abc",
+				"DB_PASSWORD": "secret",
+			},
+			wantErr: strconv.ErrSyntax,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			parser, err := NewParserWithSource(source, tt.opts...)
+			assert.NoError(t, err)
+			result := &target{}
+			err = parser.Parse(result)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantResult, result)
+		})
+	}
+}
+
+func TestNewEnvSource(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefix     string
+		rootPrefix string
+		wantSource Source
+		wantErr    error
+	}{
+		{
+			name:       "Prefixes Without Separators",
+			prefix:     "APP",
+			rootPrefix: "production",
+			wantSource: &envSource{
+				prefix:     "APP_",
+				rootPrefix: "production/",
+			},
+		},
+		{
+			name:   "Prefix With Separator",
+			prefix: "APP_",
+			wantSource: &envSource{
+				prefix: "APP_",
+			},
+		},
+		{
+			name:    "Empty Prefix",
+			wantErr: ErrEmptyEnvPrefix,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := NewEnvSource(tt.prefix, tt.rootPrefix)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantSource, source)
+		})
+	}
+}
+
+func TestParser_Parse_EnvSourceLayer(t *testing.T) {
+	type DBConfig struct {
+		Host string `consulkv:"host"`
+		Port int    `consulkv:"port"`
+	}
+	type target struct {
+		Name string   `consulkv:"name"`
+		DB   DBConfig `consulkv:"db/"`
+	}
+	t.Setenv("APP_DB_HOST", "db.env")
+	t.Setenv("APP_DB_PORT", "6432")
+	t.Setenv("APP_NAME", "")
+	envSource, err := NewEnvSource("APP", "production")
+	assert.NoError(t, err)
+	layers := []Layer{
+		{
+			Name: LayerConsul,
+			Source: NewMemorySource(map[string]string{
+				"production/name":    "billing",
+				"production/db/host": "localhost",
+				"production/db/port": "5432",
+			}),
+		},
+		{
+			Name:   LayerEnv,
+			Source: envSource,
+		},
+		{
+			Name: "flags",
+			Source: NewMemorySource(map[string]string{
+				"production/db/port": "7432",
+			}),
+		},
+	}
+	want := &target{
+		Name: "billing",
+		DB: DBConfig{
+			Host: "db.env",
+			Port: 7432,
+		},
+	}
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "Lazy",
+			opts: []Option{WithRootPrefix("production")},
+		},
+		{
+			name: "Prefetch",
+			opts: []Option{WithRootPrefix("production"), WithPrefetch(true)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParserWithLayers(layers, tt.opts...)
+			assert.NoError(t, err)
+			result := &target{}
+			report, err := parser.ParseReport(context.Background(), result)
+			assert.NoError(t, err)
+			assert.Equal(t, want, result)
+			assert.Equal(t, LayerEnv, report["DB.Host"].Layer)
+		})
+	}
+}
@@ -1,10 +1,25 @@
 package consulparser
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
 
 var (
 	//ErrNilClient defines error for client that is nil.
 	ErrNilClient = errors.New("client must not be nil")
+	//ErrNilSource defines error for source that is nil.
+	ErrNilSource = errors.New("source must not be nil")
+	//ErrUnsupportedSource defines error for an operation that the source of the parser doesn't support.
+	ErrUnsupportedSource = errors.New("operation is not supported by the source")
+	//ErrInvalidExport defines the error for a document that isn't written by `consul kv export`.
+	ErrInvalidExport = errors.New("consul kv export is not valid")
+	//ErrNilConverter defines error for converter or converted type that is nil.
+	ErrNilConverter = errors.New("converter and its type must not be nil")
+	//ErrNilParser defines error for parser that is nil.
+	ErrNilParser = errors.New("parser must not be nil")
 	//ErrNonPointerType  defines error for the value that is non-pointer type.
 	ErrNonPointerType = errors.New("value must be pointer type")
 	//ErrUnhandledKind defines error for the kind that is not handled by this library.
@@ -13,4 +28,85 @@ var (
 	ErrOverflowSet = errors.New("error in set the overflowing value to the field")
 	//ErrEmptyLayout defines the error for empty layout given.
 	ErrEmptyLayout = errors.New("layout given is an empty string")
+	//ErrEmptyTagName defines the error for empty tag name given.
+	ErrEmptyTagName = errors.New("tag name given is an empty string")
+	//ErrEmptyEnvPrefix defines the error for empty environment variable prefix given.
+	ErrEmptyEnvPrefix = errors.New("environment variable prefix given is an empty string")
+	//ErrKeyNotFound defines the error for a tagged key that doesn't exist in consul.
+	ErrKeyNotFound = errors.New("key is not found in consul")
+	//ErrUnknownPolicy defines the error for a missing key policy that is not handled by this library.
+	ErrUnknownPolicy = errors.New("unknown missing key policy")
+	//ErrInvalidKeyTemplate defines the error for a key template that can't be executed with the key variables.
+	ErrInvalidKeyTemplate = errors.New("key template is not valid")
+	//ErrInvalidList defines the error for a list value that starts like a JSON array but isn't one.
+	ErrInvalidList = errors.New("list value is not a valid JSON array")
+	//ErrInvalidDocument defines the error for a value that can't be decoded with the format of its tag.
+	ErrInvalidDocument = errors.New("value is not a valid document of the tag format")
+	//ErrInvalidIndex defines the error for a subfolder of a list of structs whose name isn't an index.
+	ErrInvalidIndex = errors.New("subfolder name is not a list index")
+	//ErrTypeMismatch defines the error for comparing values that don't have the same type.
+	ErrTypeMismatch = errors.New("values must have the same type")
+	//ErrTxnRollback defines the error for a snapshot transaction that is rolled back by Consul.
+	ErrTxnRollback = errors.New("snapshot transaction is rolled back")
+	//ErrInconsistentSnapshot defines the error for chunked snapshot reads that never agree on one index.
+	ErrInconsistentSnapshot = errors.New("snapshot chunks are read from different indexes")
 )
+
+//FieldError defines the error for a field that can't get its value from consul.
+//The raw value is kept on the error, but it is left out of the message because it may be a secret.
+type FieldError struct {
+	//Path is the Go field path, e.g. Database.Primary.Port.
+	Path string
+	//Key is the consul key of the field.
+	Key string
+	//Value is the raw string value read from consul.
+	Value string
+	//Kind is the kind of the field after dereferencing its pointers.
+	Kind reflect.Kind
+	//Err is the underlying error.
+	Err error
+}
+
+func (fieldErr *FieldError) Error() string {
+	return fmt.Sprintf("field %s with key %q of kind %s: %s", fieldErr.Path, fieldErr.Key, fieldErr.Kind, fieldErr.Err)
+}
+
+//Unwrap returns the underlying error, so FieldError works with errors.Is and errors.As.
+func (fieldErr *FieldError) Unwrap() error {
+	return fieldErr.Err
+}
+
+//MultiError defines the error that aggregates every field error of a single Parse.
+//It is returned when the parser is built with WithCollectErrors.
+type MultiError struct {
+	Errors []error
+}
+
+func (multiErr *MultiError) Error() string {
+	messages := make([]string, 0, len(multiErr.Errors))
+	for _, err := range multiErr.Errors {
+		messages = append(messages, "\t* "+err.Error())
+	}
+	return fmt.Sprintf("%d errors occurred while parsing:\n%s", len(multiErr.Errors), strings.Join(messages, "\n"))
+}
+
+//Unwrap returns the aggregated errors, so errors.Is and errors.As match any of them.
+func (multiErr *MultiError) Unwrap() []error {
+	return multiErr.Errors
+}
+
+//newFieldError wraps err with the field information.
+//Errors that already are a FieldError come from a nested field and are returned as they are.
+func newFieldError(path, key, value string, typ reflect.Type, err error) error {
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		return err
+	}
+	return &FieldError{
+		Path:  path,
+		Key:   key,
+		Value: value,
+		Kind:  indirectType(typ).Kind(),
+		Err:   err,
+	}
+}
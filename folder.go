@@ -0,0 +1,163 @@
+package consulparser
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+//assignSubfolders parses a struct from every subfolder of the folder of the field with the subfolder as prefix.
+//Maps are indexed by the subfolder names, while the subfolder names of slices and arrays must be list indexes.
+//Arrays put every element at its index and leave the missing ones zero,
+//failing with ErrOverflowSet when an index isn't below their length.
+//Slices compact the gaps between the indexes, e.g. after removing a subfolder, keeping the elements in order.
+func (parser *Parser) assignSubfolders(state *parseState, val reflect.Value, sc scope, value string) (err error) {
+	typ := val.Type()
+	if value != "" || (typ.Kind() == reflect.Map && typ.Key().Kind() != reflect.String) {
+		err = ErrUnhandledKind
+		return
+	}
+	if sc.subfolders == nil {
+		return
+	}
+	names := sc.subfolders
+	var (
+		collVal reflect.Value
+		indexes []int
+	)
+	switch typ.Kind() {
+	case reflect.Map:
+		collVal = reflect.MakeMapWithSize(typ, len(names))
+	case reflect.Array:
+		names, indexes, err = sortIndexes(names)
+		if err != nil {
+			return
+		}
+		if len(indexes) > 0 && indexes[len(indexes)-1] >= typ.Len() {
+			err = fmt.Errorf("%w: %s", ErrOverflowSet, names[len(names)-1])
+			return
+		}
+		collVal = reflect.New(typ).Elem()
+	default:
+		names, _, err = sortIndexes(names)
+		if err != nil {
+			return
+		}
+		collVal = reflect.MakeSlice(typ, len(names), len(names))
+	}
+	for index, name := range names {
+		elemScope := scope{
+			path:   fmt.Sprintf("%s[%s]", sc.path, name),
+			prefix: sc.prefix + name + "/",
+		}
+		elemVal := reflect.New(typ.Elem()).Elem()
+		err = parser.assign(state, elemVal, elemScope, "")
+		if err != nil {
+			return
+		}
+		if typ.Kind() == reflect.Map {
+			collVal.SetMapIndex(reflect.ValueOf(name).Convert(typ.Key()), elemVal)
+			continue
+		}
+		if indexes != nil {
+			index = indexes[index]
+		}
+		collVal.Index(index).Set(elemVal)
+	}
+	val.Set(collVal)
+	return
+}
+
+//sortIndexes sorts the subfolder names by their numeric value and returns those values in the same order.
+func sortIndexes(names []string) (sorted []string, indexes []int, err error) {
+	values := make(map[string]int, len(names))
+	for _, name := range names {
+		var index int
+		index, err = strconv.Atoi(name)
+		if err != nil || index < 0 {
+			err = fmt.Errorf("%w: %s", ErrInvalidIndex, name)
+			return
+		}
+		values[name] = index
+	}
+	sorted = append([]string(nil), names...)
+	sort.Slice(sorted, func(first, second int) bool {
+		return values[sorted[first]] < values[sorted[second]]
+	})
+	indexes = make([]int, 0, len(sorted))
+	for _, name := range sorted {
+		indexes = append(indexes, values[name])
+	}
+	return
+}
+
+//getChildren reads the values of the direct children of the folder from every layer of the parser,
+//indexed by their key relative to the folder, along with the sorted names of its subfolders.
+//The layers with a higher precedence override the others.
+//The report holds the highest layer having a key under the folder and the highest ModifyIndex of those keys.
+func (parser *Parser) getChildren(state *parseState, folder string) (children map[string]string, subfolders []string, fieldReport FieldReport, err error) {
+	fieldReport.Key = folder
+	if folder == "" {
+		return
+	}
+	err = state.ctx.Err()
+	if err != nil {
+		return
+	}
+	subfolderSet := make(map[string]bool)
+	for index, layer := range parser.layers {
+		var list api.KVPairs
+		if state.pairs != nil {
+			for _, pair := range state.pairs[index] {
+				list = append(list, pair)
+			}
+		} else {
+			list, err = layer.Source.List(state.ctx, folder)
+			if err != nil {
+				return
+			}
+		}
+		for _, pair := range list {
+			key, subfolder, ok := childKey(folder, pair.Key)
+			if !ok {
+				continue
+			}
+			switch {
+			case subfolder != "":
+				subfolderSet[subfolder] = true
+			default:
+				if children == nil {
+					children = make(map[string]string)
+				}
+				children[key] = string(pair.Value)
+			}
+			fieldReport.Layer = layer.Name
+			if pair.ModifyIndex > fieldReport.ModifyIndex {
+				fieldReport.ModifyIndex = pair.ModifyIndex
+			}
+		}
+	}
+	for subfolder := range subfolderSet {
+		subfolders = append(subfolders, subfolder)
+	}
+	sort.Strings(subfolders)
+	return
+}
+
+//childKey returns the key relative to the folder when the key is a direct child of the folder,
+//or the name of the subfolder holding the key. The folder itself is neither.
+func childKey(folder, key string) (child, subfolder string, ok bool) {
+	if !strings.HasPrefix(key, folder) || key == folder {
+		return
+	}
+	child = strings.TrimPrefix(key, folder)
+	ok = true
+	if index := strings.Index(child, "/"); index >= 0 {
+		child, subfolder = "", child[:index]
+	}
+	return
+}
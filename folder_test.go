@@ -0,0 +1,234 @@
+package consulparser
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParser_Parse_Subfolders(t *testing.T) {
+	type upstream struct {
+		Host string `consulkv:"host"`
+		Port int    `consulkv:"port,default=80"`
+	}
+	type target struct {
+		Upstreams []upstream            `consulkv:"upstreams"`
+		Backups   []*upstream           `consulkv:"backups/"`
+		Pinned    [2]upstream           `consulkv:"pinned"`
+		Named     map[string]upstream   `consulkv:"named"`
+		Mirrors   *map[string]*upstream `consulkv:"mirrors"`
+	}
+	values := map[string]string{
+		"app/upstreams/0/host":   "a.internal",
+		"app/upstreams/0/port":   "8080",
+		"app/upstreams/10/host":  "c.internal",
+		"app/upstreams/2/host":   "b.internal",
+		"app/backups/0/host":     "backup.internal",
+		"app/pinned/1/host":      "pinned.internal",
+		"app/named/billing/host": "billing.internal",
+		"app/named/search/host":  "search.internal",
+		"app/named/search/port":  "9200",
+		"app/mirrors/eu/host":    "eu.internal",
+	}
+	tests := []struct {
+		name       string
+		values     map[string]string
+		opts       []Option
+		wantResult *target
+		wantErr    error
+		wantPath   string
+	}{
+		{
+			name:   "Indexed and Named Subfolders",
+			values: values,
+			opts:   []Option{WithRootPrefix("app")},
+			wantResult: &target{
+				Upstreams: []upstream{
+					{Host: "a.internal", Port: 8080},
+					{Host: "b.internal", Port: 80},
+					{Host: "c.internal", Port: 80},
+				},
+				Backups: []*upstream{
+					{Host: "backup.internal", Port: 80},
+				},
+				Pinned: [2]upstream{
+					{},
+					{Host: "pinned.internal", Port: 80},
+				},
+				Named: map[string]upstream{
+					"billing": {Host: "billing.internal", Port: 80},
+					"search":  {Host: "search.internal", Port: 9200},
+				},
+				Mirrors: &map[string]*upstream{
+					"eu": {Host: "eu.internal", Port: 80},
+				},
+			},
+		},
+		{
+			name:   "Prefetched Subfolders",
+			values: values,
+			opts:   []Option{WithRootPrefix("app"), WithPrefetch(true)},
+			wantResult: &target{
+				Upstreams: []upstream{
+					{Host: "a.internal", Port: 8080},
+					{Host: "b.internal", Port: 80},
+					{Host: "c.internal", Port: 80},
+				},
+				Backups: []*upstream{
+					{Host: "backup.internal", Port: 80},
+				},
+				Pinned: [2]upstream{
+					{},
+					{Host: "pinned.internal", Port: 80},
+				},
+				Named: map[string]upstream{
+					"billing": {Host: "billing.internal", Port: 80},
+					"search":  {Host: "search.internal", Port: 9200},
+				},
+				Mirrors: &map[string]*upstream{
+					"eu": {Host: "eu.internal", Port: 80},
+				},
+			},
+		},
+		{
+			name: "Invalid Index",
+			values: map[string]string{
+				"upstreams/primary/host": "a.internal",
+			},
+			wantErr:  ErrInvalidIndex,
+			wantPath: "Upstreams",
+		},
+		{
+			name: "Array Overflow",
+			values: map[string]string{
+				"pinned/0/host": "a.internal",
+				"pinned/1/host": "b.internal",
+				"pinned/2/host": "c.internal",
+			},
+			wantErr:  ErrOverflowSet,
+			wantPath: "Pinned",
+		},
+		{
+			name: "Sparse Array Overflow",
+			values: map[string]string{
+				"pinned/5/host": "a.internal",
+			},
+			wantErr:  ErrOverflowSet,
+			wantPath: "Pinned",
+		},
+		{
+			name: "Invalid Element Field",
+			values: map[string]string{
+				"named/search/host": "search.internal",
+				"named/search/port": "abc",
+			},
+			wantErr:  strconv.ErrSyntax,
+			wantPath: "Named[search].Port",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithMissingKeyPolicy(MissingKeySkip)}, tt.opts...)
+			parser, err := NewParserWithSource(NewMemorySource(tt.values), opts...)
+			assert.NoError(t, err)
+			result := &target{}
+			err = parser.Parse(result)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				var fieldErr *FieldError
+				assert.True(t, errors.As(err, &fieldErr))
+				assert.Equal(t, tt.wantPath, fieldErr.Path)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantResult, result)
+		})
+	}
+}
+
+func TestParser_Watch_Subfolders(t *testing.T) {
+	type upstream struct {
+		Host string `consulkv:"host"`
+	}
+	type target struct {
+		Upstreams []upstream `consulkv:"upstreams"`
+	}
+	source := NewMemorySource(map[string]string{
+		"upstreams/0/host": "a.internal",
+	})
+	parser, err := NewParserWithSource(source)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var values [][]upstream
+	result := &target{}
+	err = parser.Watch(ctx, result, func(event WatchEvent) {
+		assert.NoError(t, event.Err)
+		values = append(values, result.Upstreams)
+		switch len(values) {
+		case 1:
+			source.Set("upstreams/1/host", "b.internal")
+		default:
+			cancel()
+		}
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, [][]upstream{
+		{{Host: "a.internal"}},
+		{{Host: "a.internal"}, {Host: "b.internal"}},
+	}, values)
+}
+
+func TestParser_Parse_SubfoldersAbsoluteKey(t *testing.T) {
+	type upstream struct {
+		Host   string `consulkv:"host"`
+		Shared string `consulkv:"/shared/token"`
+	}
+	type target struct {
+		Ups []upstream `consulkv:"up/"`
+	}
+	values := map[string]string{
+		"up/0/host":    "a.internal",
+		"up/1/host":    "b.internal",
+		"shared/token": "secret",
+	}
+	want := &target{
+		Ups: []upstream{
+			{Host: "a.internal", Shared: "secret"},
+			{Host: "b.internal", Shared: "secret"},
+		},
+	}
+	for _, opts := range [][]Option{nil, {WithPrefetch(true)}} {
+		parser, err := NewParserWithSource(NewMemorySource(values), opts...)
+		assert.NoError(t, err)
+		result := &target{}
+		assert.NoError(t, parser.Parse(result))
+		assert.Equal(t, want, result)
+	}
+
+	source := NewMemorySource(values)
+	parser, err := NewParserWithSource(source)
+	assert.NoError(t, err)
+	//Prefetch, ParseSnapshot and Watch read the absolute keys of the structs along with their folder.
+	assert.Equal(t, []string{"up/", "shared/token"}, parser.(*Parser).targetKeys(reflect.ValueOf(&target{}).Elem()))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var tokens []string
+	result := &target{}
+	err = parser.Watch(ctx, result, func(event WatchEvent) {
+		assert.NoError(t, event.Err)
+		tokens = append(tokens, result.Ups[0].Shared)
+		switch len(tokens) {
+		case 1:
+			source.Set("shared/token", "rotated")
+		default:
+			cancel()
+		}
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []string{"secret", "rotated"}, tokens)
+}
@@ -0,0 +1,143 @@
+package consulparser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+//scope defines where a value being parsed lives in the target.
+type scope struct {
+	//path is the Go field path, e.g. Database.Primary.
+	path string
+	//prefix is the consul key prefix of the children of a struct, either empty or ending with a slash.
+	prefix string
+	//options holds the options of the tag of the field, they aren't shared with its children.
+	options tagOptions
+	//children holds the values under the folder of a map field indexed by their relative key.
+	children map[string]string
+	//subfolders holds the names of the subfolders under the folder of a field holding structs.
+	subfolders []string
+}
+
+//field returns the scope of the named field, which shares the prefix of its parent.
+func (sc scope) field(name string) scope {
+	path := name
+	if sc.path != "" {
+		path = sc.path + "." + name
+	}
+	return scope{
+		path:   path,
+		prefix: sc.prefix,
+	}
+}
+
+//resolveKey joins the key of a field to the prefix of its parent.
+//Keys starting with a slash are absolute and ignore the prefix.
+//Fields without a key stay without a key.
+func resolveKey(prefix, key string) string {
+	switch {
+	case key == "":
+		return ""
+	case strings.HasPrefix(key, "/"):
+		return strings.TrimPrefix(key, "/")
+	default:
+		return prefix + key
+	}
+}
+
+//resolvePrefix returns the prefix for the children of a struct field tagged with the key.
+//A struct field without a key shares the prefix of its parent.
+func resolvePrefix(prefix, key string) string {
+	if key == "" {
+		return prefix
+	}
+	resolved := resolveKey(prefix, key)
+	if resolved != "" && !strings.HasSuffix(resolved, "/") {
+		resolved += "/"
+	}
+	return resolved
+}
+
+//resolveFieldKey returns the key of a field that isn't a nested struct.
+//Maps and lists of structs are read from a folder, so their key ends with a slash,
+//unless the tag has a format to decode them from a single value.
+func (parser *Parser) resolveFieldKey(prefix string, options tagOptions, typ reflect.Type) string {
+	if options.key != "" && options.format == "" && parser.isFolder(indirectType(typ)) {
+		return resolvePrefix(prefix, options.key)
+	}
+	return resolveKey(prefix, options.key)
+}
+
+//expandKey executes the template variables of the parser in the key and puts it under the root prefix.
+//Fields without a key stay without a key.
+func (parser *Parser) expandKey(key string) (expanded string, err error) {
+	if key == "" {
+		return
+	}
+	expanded = key
+	if strings.Contains(key, "{{") {
+		var tmpl *template.Template
+		tmpl, err = parser.keyTemplate(key)
+		if err != nil {
+			expanded = key
+			err = fmt.Errorf("%w: %s", ErrInvalidKeyTemplate, err)
+			return
+		}
+		var builder strings.Builder
+		err = tmpl.Execute(&builder, parser.keyVariables)
+		if err != nil {
+			expanded = key
+			err = fmt.Errorf("%w: %s", ErrInvalidKeyTemplate, err)
+			return
+		}
+		expanded = builder.String()
+	}
+	expanded = parser.rootPrefix + expanded
+	return
+}
+
+//keyTemplate returns the parsed template of the key, which is cached on the parser
+//since the same keys are expanded on every parse.
+func (parser *Parser) keyTemplate(key string) (tmpl *template.Template, err error) {
+	if cached, ok := parser.templates.Load(key); ok {
+		tmpl = cached.(*template.Template)
+		return
+	}
+	tmpl, err = template.New(key).Option("missingkey=error").Parse(key)
+	if err != nil {
+		return
+	}
+	parser.templates.Store(key, tmpl)
+	return
+}
+
+//isNestedStruct reports whether the type is a struct that parse walks field by field.
+//Structs decoding themselves with an unmarshaler or a converter of the parser are assigned from their own key instead.
+func (parser *Parser) isNestedStruct(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct && typ.String() != timeType && !isUnmarshaler(typ) && parser.converters[typ] == nil
+}
+
+//isFolder reports whether the type is filled from a folder: a map with the children of the folder,
+//or a slice or an array of structs with its subfolders.
+func (parser *Parser) isFolder(typ reflect.Type) bool {
+	switch {
+	case isUnmarshaler(typ), parser.converters[typ] != nil:
+		return false
+	case typ.Kind() == reflect.Map:
+		return true
+	case typ.Kind() == reflect.Slice, typ.Kind() == reflect.Array:
+		return parser.isNestedStruct(indirectType(typ.Elem()))
+	default:
+		return false
+	}
+}
+
+//indirectType dereferences the pointer types until it reaches a non-pointer type.
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
@@ -0,0 +1,188 @@
+package consulparser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		key    string
+		want   string
+	}{
+		{
+			name:   "Relative Key",
+			prefix: "database/primary/",
+			key:    "host",
+			want:   "database/primary/host",
+		},
+		{
+			name:   "Absolute Key",
+			prefix: "database/primary/",
+			key:    "/global/timeout",
+			want:   "global/timeout",
+		},
+		{
+			name:   "Empty Key",
+			prefix: "database/primary/",
+			key:    "",
+			want:   "",
+		},
+		{
+			name:   "Empty Prefix",
+			prefix: "",
+			key:    "host",
+			want:   "host",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, resolveKey(tt.prefix, tt.key))
+		})
+	}
+}
+
+func TestResolvePrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		key    string
+		want   string
+	}{
+		{
+			name:   "Key with Trailing Slash",
+			prefix: "database/",
+			key:    "primary/",
+			want:   "database/primary/",
+		},
+		{
+			name:   "Key without Trailing Slash",
+			prefix: "database/",
+			key:    "primary",
+			want:   "database/primary/",
+		},
+		{
+			name:   "Absolute Key",
+			prefix: "database/",
+			key:    "/replica/",
+			want:   "replica/",
+		},
+		{
+			name:   "Absolute Root",
+			prefix: "database/",
+			key:    "/",
+			want:   "",
+		},
+		{
+			name:   "Empty Key Keeps the Prefix",
+			prefix: "database/",
+			key:    "",
+			want:   "database/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, resolvePrefix(tt.prefix, tt.key))
+		})
+	}
+}
+
+func TestParser_expandKey(t *testing.T) {
+	tests := []struct {
+		name         string
+		rootPrefix   string
+		keyVariables map[string]string
+		key          string
+		want         string
+		wantErr      error
+	}{
+		{
+			name:       "Root Prefix",
+			rootPrefix: "production/",
+			key:        "db/host",
+			want:       "production/db/host",
+		},
+		{
+			name:       "Template Variables",
+			rootPrefix: "root/",
+			keyVariables: map[string]string{
+				"Env":     "staging",
+				"Service": "billing",
+			},
+			key:  "{{.Env}}/{{.Service}}/db/host",
+			want: "root/staging/billing/db/host",
+		},
+		{
+			name:    "Missing Template Variable",
+			key:     "{{.Env}}/db/host",
+			want:    "{{.Env}}/db/host",
+			wantErr: ErrInvalidKeyTemplate,
+		},
+		{
+			name:    "Broken Template",
+			key:     "{{.Env/db/host",
+			want:    "{{.Env/db/host",
+			wantErr: ErrInvalidKeyTemplate,
+		},
+		{
+			name:       "Empty Key",
+			rootPrefix: "production/",
+			key:        "",
+			want:       "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := &Parser{
+				rootPrefix:   tt.rootPrefix,
+				keyVariables: tt.keyVariables,
+			}
+			got, err := parser.expandKey(tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parser.expandKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParser_keyTemplate(t *testing.T) {
+	parser := &Parser{}
+	first, err := parser.keyTemplate("{{.Env}}/db/host")
+	assert.NoError(t, err)
+	second, err := parser.keyTemplate("{{.Env}}/db/host")
+	assert.NoError(t, err)
+	assert.Same(t, first, second)
+	//Broken templates aren't cached.
+	_, err = parser.keyTemplate("{{.Env/db/host")
+	assert.Error(t, err)
+	_, ok := parser.templates.Load("{{.Env/db/host")
+	assert.False(t, ok)
+}
+
+func TestParser_Parse_InvalidKeyTemplate(t *testing.T) {
+	type target struct {
+		Password string `consulkv:"db/password"`
+		Host     string `consulkv:"{{.Env}}/db/host"`
+	}
+	parser, err := NewParserWithSource(NewMemorySource(map[string]string{
+		"db/password": "hunter2",
+	}))
+	assert.NoError(t, err)
+	err = parser.Parse(&target{})
+	assert.ErrorIs(t, err, ErrInvalidKeyTemplate)
+	var fieldErr *FieldError
+	assert.True(t, errors.As(err, &fieldErr))
+	//The value of the previous field doesn't leak into the error.
+	assert.Equal(t, &FieldError{
+		Path: "Host",
+		Key:  "{{.Env}}/db/host",
+		Kind: reflect.String,
+		Err:  fieldErr.Err,
+	}, fieldErr)
+}
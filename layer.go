@@ -0,0 +1,46 @@
+package consulparser
+
+const (
+	//LayerConsul is the name of the layer of the parsers built with NewParser.
+	LayerConsul = "consul"
+	//LayerSource is the name of the layer of the parsers built with NewParserWithSource.
+	LayerSource = "source"
+	//LayerEnv is the layer reported for the fields overridden by an environment variable.
+	LayerEnv = "env"
+	//LayerDefault is the layer reported for the fields assigned from the default option of their tag.
+	LayerDefault = "default"
+)
+
+//Layer defines a named source in the precedence order of a parser.
+type Layer struct {
+	//Name identifies the layer in the reports of the parsed fields, e.g. "file" or "flags".
+	Name string
+	//Source is the store read by the layer.
+	Source Source
+}
+
+//NewParserWithLayers initialize a new parser resolving every tagged key through the layers.
+//The layers are given from the lowest to the highest precedence, e.g. a file, then consul, then flags,
+//so a key is taken from the last layer holding it.
+//ParseSnapshot and Watch are only supported by parsers with a single layer.
+func NewParserWithLayers(layers []Layer, opts ...Option) (parser ParserIface, err error) {
+	if len(layers) == 0 {
+		return nil, ErrNilSource
+	}
+	for _, layer := range layers {
+		if layer.Source == nil {
+			return nil, ErrNilSource
+		}
+	}
+	newParser := &Parser{
+		layers: append([]Layer(nil), layers...),
+	}
+	for _, opt := range opts {
+		err = opt(newParser)
+		if err != nil {
+			return
+		}
+	}
+	parser = newParser
+	return
+}
@@ -0,0 +1,155 @@
+package consulparser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewParserWithLayers(t *testing.T) {
+	tests := []struct {
+		name    string
+		layers  []Layer
+		opts    []Option
+		wantErr error
+	}{
+		{
+			name: "Valid Layers",
+			layers: []Layer{
+				{Name: "file", Source: NewMemorySource(nil)},
+				{Name: "flags", Source: NewMemorySource(nil)},
+			},
+			opts: []Option{WithPrefetch(true)},
+		},
+		{
+			name:    "No Layer",
+			wantErr: ErrNilSource,
+		},
+		{
+			name: "Nil Source",
+			layers: []Layer{
+				{Name: "file", Source: NewMemorySource(nil)},
+				{Name: "flags"},
+			},
+			wantErr: ErrNilSource,
+		},
+		{
+			name: "Failed Option",
+			layers: []Layer{
+				{Name: "file", Source: NewMemorySource(nil)},
+			},
+			opts:    []Option{WithTimeLayout("")},
+			wantErr: ErrEmptyLayout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParserWithLayers(tt.layers, tt.opts...)
+			if err != tt.wantErr {
+				t.Errorf("NewParserWithLayers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr != nil {
+				assert.Nil(t, parser)
+				return
+			}
+			assert.Equal(t, &Parser{
+				layers:   tt.layers,
+				prefetch: true,
+			}, parser)
+		})
+	}
+}
+
+func TestParser_ParseReport_Layers(t *testing.T) {
+	type DBConfig struct {
+		Host     string `consulkv:"host"`
+		Port     int    `consulkv:"port,default=5432"`
+		User     string `consulkv:"user"`
+		Password string `consulkv:"password" env:"DB_PASSWORD"`
+	}
+	type target struct {
+		Name    string   `consulkv:"name"`
+		Debug   bool     `consulkv:"debug"`
+		DB      DBConfig `consulkv:"db/"`
+		Timeout int      `consulkv:"timeout"`
+	}
+	layers := []Layer{
+		{
+			Name: "file",
+			Source: NewMemorySource(map[string]string{
+				"name":        "billing",
+				"db/host":     "localhost",
+				"db/user":     "billing",
+				"db/password": "file-secret",
+			}),
+		},
+		{
+			Name: "consul",
+			Source: NewMemorySource(map[string]string{
+				"db/host": "db.local",
+				"debug":   "false",
+			}),
+		},
+		{
+			Name: "flags",
+			Source: NewMemorySource(map[string]string{
+				"debug": "true",
+			}),
+		},
+	}
+	t.Setenv("DB_PASSWORD", "env-secret")
+	for _, prefetch := range []bool{false, true} {
+		parser, err := NewParserWithLayers(layers,
+			WithPrefetch(prefetch),
+			WithEnvOverrides(""),
+			WithMissingKeyPolicy(MissingKeySkip),
+		)
+		assert.NoError(t, err)
+		result := &target{
+			Timeout: 30,
+		}
+		report, err := parser.ParseReport(context.Background(), result)
+		assert.NoError(t, err)
+		assert.Equal(t, &target{
+			Name:  "billing",
+			Debug: true,
+			DB: DBConfig{
+				Host:     "db.local",
+				Port:     5432,
+				User:     "billing",
+				Password: "env-secret",
+			},
+			Timeout: 30,
+		}, result)
+		assert.Equal(t, map[string]string{
+			"Name":        "file",
+			"Debug":       "flags",
+			"DB.Host":     "consul",
+			"DB.Port":     LayerDefault,
+			"DB.User":     "file",
+			"DB.Password": LayerEnv,
+		}, reportLayers(report))
+	}
+	//Only a single source can be read at one index or watched.
+	parser, err := NewParserWithLayers(layers)
+	assert.NoError(t, err)
+	_, err = parser.ParseSnapshot(&target{})
+	assert.Equal(t, ErrUnsupportedSource, err)
+	err = parser.Watch(context.Background(), &target{}, func(WatchEvent) {
+		t.Error("onChange must not be called")
+	})
+	assert.Equal(t, ErrUnsupportedSource, err)
+}
+
+//reportLayers returns the layer of every reported field that got a value.
+func reportLayers(report Report) (layers map[string]string) {
+	layers = make(map[string]string, len(report))
+	for path, fieldReport := range report {
+		if fieldReport.Layer != "" {
+			layers[path] = fieldReport.Layer
+		}
+	}
+	return
+}
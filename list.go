@@ -0,0 +1,84 @@
+package consulparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//defaultSeparator splits the list values when the tag has no separator option.
+const defaultSeparator = ","
+
+//assignList assigns the elements of the value to a slice or an array with the usual conversion.
+//Values starting with a bracket are decoded as a JSON array, the others are split with the separator of the tag
+//and their elements are trimmed. A []byte is assigned the raw value instead.
+//An array fails with ErrOverflowSet when there are more elements than its length.
+func (parser *Parser) assignList(state *parseState, val reflect.Value, sc scope, value string) (err error) {
+	elemType := val.Type().Elem()
+	//Structs are walked field by field, so they are read from subfolders instead of a single value.
+	if parser.isNestedStruct(indirectType(elemType)) {
+		err = parser.assignSubfolders(state, val, sc, value)
+		return
+	}
+	if value == "" {
+		return
+	}
+	if val.Kind() == reflect.Slice && elemType.Kind() == reflect.Uint8 {
+		val.SetBytes([]byte(value))
+		return
+	}
+	elements, err := splitList(value, sc.options.separator)
+	if err != nil {
+		return
+	}
+	var listVal reflect.Value
+	switch val.Kind() {
+	case reflect.Array:
+		if len(elements) > val.Len() {
+			err = ErrOverflowSet
+			return
+		}
+		listVal = reflect.New(val.Type()).Elem()
+	default:
+		listVal = reflect.MakeSlice(val.Type(), len(elements), len(elements))
+	}
+	for index, element := range elements {
+		err = parser.assign(state, listVal.Index(index), sc, element)
+		if err != nil {
+			err = fmt.Errorf("element %d: %w", index, err)
+			return
+		}
+	}
+	val.Set(listVal)
+	return
+}
+
+//splitList returns the elements of a JSON array or of a value delimited by the separator.
+//JSON strings are unquoted, the other JSON values keep their literal and null is an empty element.
+func splitList(value, separator string) (elements []string, err error) {
+	if strings.HasPrefix(strings.TrimSpace(value), "[") {
+		var rawElements []json.RawMessage
+		err = json.Unmarshal([]byte(value), &rawElements)
+		if err != nil {
+			err = fmt.Errorf("%w: %s", ErrInvalidList, err)
+			return
+		}
+		elements = make([]string, 0, len(rawElements))
+		for _, rawElement := range rawElements {
+			var element string
+			if json.Unmarshal(rawElement, &element) != nil {
+				element = string(rawElement)
+			}
+			elements = append(elements, element)
+		}
+		return
+	}
+	if separator == "" {
+		separator = defaultSeparator
+	}
+	for _, element := range strings.Split(value, separator) {
+		elements = append(elements, strings.TrimSpace(element))
+	}
+	return
+}
@@ -0,0 +1,90 @@
+package consulparser
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParser_Parse_List(t *testing.T) {
+	type target struct {
+		Brokers  []string         `consulkv:"kafka/brokers,sep=,"`
+		Origins  []string         `consulkv:"origins,sep=;"`
+		Ports    []int            `consulkv:"ports"`
+		Weights  []*float64       `consulkv:"weights"`
+		Timeouts *[]time.Duration `consulkv:"timeouts"`
+		Flags    [3]bool          `consulkv:"flags"`
+		Matrix   [][]int          `consulkv:"matrix"`
+		Raw      []byte           `consulkv:"raw"`
+	}
+	first, third := 0.5, 2.0
+	timeouts := []time.Duration{time.Second, time.Minute}
+	tests := []struct {
+		name       string
+		values     map[string]string
+		wantResult *target
+		wantErr    error
+	}{
+		{
+			name: "Delimited and JSON Values",
+			values: map[string]string{
+				"kafka/brokers": "kafka-1:9092, kafka-2:9092",
+				"origins":       "https://a.example;https://b.example",
+				"ports":         "[8080, 8081]",
+				"weights":       "[0.5, null, 2]",
+				"timeouts":      `["1s", "1m"]`,
+				"flags":         "true,false",
+				"matrix":        "[[1, 2], [3]]",
+				"raw":           "a,b",
+			},
+			wantResult: &target{
+				Brokers:  []string{"kafka-1:9092", "kafka-2:9092"},
+				Origins:  []string{"https://a.example", "https://b.example"},
+				Ports:    []int{8080, 8081},
+				Weights:  []*float64{&first, nil, &third},
+				Timeouts: &timeouts,
+				Flags:    [3]bool{true, false, false},
+				Matrix:   [][]int{{1, 2}, {3}},
+				Raw:      []byte("a,b"),
+			},
+		},
+		{
+			name: "Invalid Element",
+			values: map[string]string{
+				"ports": "8080,abc",
+			},
+			wantErr: strconv.ErrSyntax,
+		},
+		{
+			name: "Invalid JSON Array",
+			values: map[string]string{
+				"ports": "[8080,",
+			},
+			wantErr: ErrInvalidList,
+		},
+		{
+			name: "Array Overflow",
+			values: map[string]string{
+				"flags": "true,false,true,false",
+			},
+			wantErr: ErrOverflowSet,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParserWithSource(NewMemorySource(tt.values), WithMissingKeyPolicy(MissingKeySkip))
+			assert.NoError(t, err)
+			result := &target{}
+			err = parser.Parse(result)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, &target{}, result)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantResult, result)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package consulparser
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+//Live defines the holder of a config that is published with an atomic pointer swap.
+//Every reload parses into a fresh T, so readers calling Load always see a complete value
+//that is never mutated afterwards, without taking any lock.
+type Live[T any] struct {
+	parser ParserIface
+	value  atomic.Pointer[T]
+}
+
+//NewLive parses the first value of T with the parser and returns the holder publishing it.
+func NewLive[T any](ctx context.Context, parser ParserIface) (live *Live[T], err error) {
+	if parser == nil {
+		err = ErrNilParser
+		return
+	}
+	newLive := &Live[T]{
+		parser: parser,
+	}
+	_, err = newLive.Reload(ctx)
+	if err != nil {
+		return
+	}
+	live = newLive
+	return
+}
+
+//Load returns the latest published value.
+//The value is shared by all readers and must be treated as read-only.
+func (live *Live[T]) Load() *T {
+	return live.value.Load()
+}
+
+//Reload parses a fresh T and publishes it.
+//The returned changes list the fields that differ from the previously published value.
+//The published value is kept when parsing fails.
+func (live *Live[T]) Reload(ctx context.Context) (changes []Change, err error) {
+	fresh := new(T)
+	err = live.parser.ParseContext(ctx, fresh)
+	if err != nil {
+		return
+	}
+	if previous := live.value.Swap(fresh); previous != nil {
+		changes, err = live.parser.Diff(previous, fresh)
+	}
+	return
+}
+
+//Watch publishes a fresh T on every change reported by the Watch of the parser until the ctx is done.
+//onChange may be nil; otherwise it receives every event with the published value as its Target.
+func (live *Live[T]) Watch(ctx context.Context, onChange func(WatchEvent)) (err error) {
+	scratch := new(T)
+	err = live.parser.Watch(ctx, scratch, func(event WatchEvent) {
+		if event.Err == nil {
+			//The parser never mutates the values behind the pointers of a previous parse,
+			//so a shallow copy of the scratch value is safe to share.
+			fresh := new(T)
+			*fresh = *scratch
+			live.value.Store(fresh)
+		}
+		if onChange == nil {
+			return
+		}
+		event.Target = live.Load()
+		onChange(event)
+	})
+	return
+}
@@ -0,0 +1,156 @@
+package consulparser
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLive(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	const (
+		responseJSON = `[
+				{
+					"LockIndex": 0,
+					"Key": "%s",
+					"Flags": 0,
+					"Value": "%s",
+					"CreateIndex": 0,
+					"ModifyIndex": 0
+				}
+			]
+		`
+	)
+	port := "5432"
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/db/port",
+		func(req *http.Request) (*http.Response, error) {
+			return httpmock.NewStringResponse(http.StatusOK, fmt.Sprintf(responseJSON, "db/port", base64.StdEncoding.EncodeToString([]byte(port)))), nil
+		},
+	)
+	type config struct {
+		Port *int `consulkv:"db/port"`
+	}
+	client, err := api.NewClient(&api.Config{
+		HttpClient: &http.Client{},
+	})
+	if err != nil {
+		t.Error("Failed to start the client!")
+	}
+	parser := &Parser{
+		layers: kvLayers(client.KV()),
+	}
+
+	_, err = NewLive[config](context.Background(), nil)
+	assert.Equal(t, ErrNilParser, err)
+
+	live, err := NewLive[config](context.Background(), parser)
+	if err != nil {
+		t.Fatalf("NewLive() error = %v", err)
+	}
+	first := live.Load()
+	assert.Equal(t, 5432, *first.Port)
+
+	//A failed reload keeps the published value.
+	port = "abc"
+	_, err = live.Reload(context.Background())
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+	assert.Same(t, first, live.Load())
+
+	//Readers keep loading complete values while reloads publish new ones.
+	port = "6543"
+	var wg sync.WaitGroup
+	for index := 0; index < 10; index++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			value := live.Load()
+			assert.NotNil(t, value.Port)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := live.Reload(context.Background())
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 6543, *live.Load().Port)
+	assert.Equal(t, 5432, *first.Port)
+
+	//A reload reports the fields that changed since the published value.
+	port = "7654"
+	previous := live.Load()
+	changes, err := live.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{
+		{
+			Path: "Port",
+			Key:  "db/port",
+			Old:  previous.Port,
+			New:  live.Load().Port,
+		},
+	}, changes)
+}
+
+func TestLive_Watch(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	type config struct {
+		Host string `consulkv:"app/db/host"`
+		Port int    `consulkv:"app/db/port"`
+	}
+	responses := map[string]api.KVPairs{
+		"": {
+			{Key: "app/db/host", Value: []byte("localhost"), ModifyIndex: 5},
+			{Key: "app/db/port", Value: []byte("5432"), ModifyIndex: 5},
+		},
+		"10": {
+			{Key: "app/db/host", Value: []byte("db.local"), ModifyIndex: 11},
+			{Key: "app/db/port", Value: []byte("5432"), ModifyIndex: 5},
+		},
+	}
+	nextIndex := map[string]uint64{
+		"":   10,
+		"10": 11,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		`=~^http://127\.0\.0\.1:8500/v1/kv/app/db/`,
+		blockingResponder(responses, nextIndex, cancel),
+	)
+	client, err := api.NewClient(&api.Config{
+		HttpClient: &http.Client{},
+	})
+	if err != nil {
+		t.Error("Failed to start the client!")
+	}
+	live := &Live[config]{
+		parser: &Parser{
+			layers: kvLayers(client.KV()),
+		},
+	}
+	var published []*config
+	err = live.Watch(ctx, func(event WatchEvent) {
+		assert.NoError(t, event.Err)
+		published = append(published, event.Target.(*config))
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []*config{
+		{Host: "localhost", Port: 5432},
+		{Host: "db.local", Port: 5432},
+	}, published)
+	assert.NotSame(t, published[0], published[1])
+	assert.Same(t, published[1], live.Load())
+}
@@ -0,0 +1,44 @@
+package consulparser
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+//assignMap assigns the children of the folder of the field to a map with the usual conversion,
+//indexed by their key relative to the folder. Only maps with string keys are handled,
+//and they can't be assigned from a single value such as a default literal.
+func (parser *Parser) assignMap(state *parseState, val reflect.Value, sc scope, value string) (err error) {
+	typ := val.Type()
+	if parser.isNestedStruct(indirectType(typ.Elem())) {
+		err = parser.assignSubfolders(state, val, sc, value)
+		return
+	}
+	if value != "" || typ.Key().Kind() != reflect.String {
+		err = ErrUnhandledKind
+		return
+	}
+	if sc.children == nil {
+		return
+	}
+	keys := make([]string, 0, len(sc.children))
+	for key := range sc.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	childScope := sc
+	childScope.children, childScope.subfolders = nil, nil
+	mapVal := reflect.MakeMapWithSize(typ, len(keys))
+	for _, key := range keys {
+		elemVal := reflect.New(typ.Elem()).Elem()
+		err = parser.assign(state, elemVal, childScope, sc.children[key])
+		if err != nil {
+			err = fmt.Errorf("child %s: %w", key, err)
+			return
+		}
+		mapVal.SetMapIndex(reflect.ValueOf(key).Convert(typ.Key()), elemVal)
+	}
+	val.Set(mapVal)
+	return
+}
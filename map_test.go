@@ -0,0 +1,151 @@
+package consulparser
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParser_Parse_Map(t *testing.T) {
+	type tenant string
+	type target struct {
+		Limits  map[string]int      `consulkv:"limits"`
+		Owners  *map[tenant]string  `consulkv:"owners/"`
+		Regions map[string][]string `consulkv:"/global/regions"`
+		Plain   map[string]string
+	}
+	values := map[string]string{
+		"app/limits/":             "",
+		"app/limits/tenantA":      "100",
+		"app/limits/tenantB":      "200",
+		"app/limits/tenantB/more": "300",
+		"app/owners/tenantA":      "alice",
+		"app/global/regions/eu":   "eu-west-1,eu-central-1",
+	}
+	tests := []struct {
+		name       string
+		layers     []Layer
+		opts       []Option
+		wantResult *target
+		wantErr    error
+	}{
+		{
+			name: "Folder Children",
+			layers: []Layer{
+				{Name: "consul", Source: NewMemorySource(values)},
+			},
+			opts: []Option{WithRootPrefix("app")},
+			wantResult: &target{
+				Limits: map[string]int{
+					"tenantA": 100,
+					"tenantB": 200,
+				},
+				Owners: &map[tenant]string{
+					"tenantA": "alice",
+				},
+				Regions: map[string][]string{
+					"eu": {"eu-west-1", "eu-central-1"},
+				},
+			},
+		},
+		{
+			name: "Layered Children",
+			layers: []Layer{
+				{Name: "consul", Source: NewMemorySource(values)},
+				{Name: "flags", Source: NewMemorySource(map[string]string{
+					"app/limits/tenantB": "250",
+					"app/limits/tenantC": "50",
+				})},
+			},
+			opts: []Option{WithRootPrefix("app"), WithPrefetch(true)},
+			wantResult: &target{
+				Limits: map[string]int{
+					"tenantA": 100,
+					"tenantB": 250,
+					"tenantC": 50,
+				},
+				Owners: &map[tenant]string{
+					"tenantA": "alice",
+				},
+				Regions: map[string][]string{
+					"eu": {"eu-west-1", "eu-central-1"},
+				},
+			},
+		},
+		{
+			name: "Empty Folder",
+			layers: []Layer{
+				{Name: "consul", Source: NewMemorySource(values)},
+			},
+			wantErr: ErrKeyNotFound,
+		},
+		{
+			name: "Invalid Child",
+			layers: []Layer{
+				{Name: "consul", Source: NewMemorySource(map[string]string{
+					"limits/tenantA":    "abc",
+					"owners/tenantA":    "alice",
+					"global/regions/eu": "eu-west-1",
+				})},
+			},
+			wantErr: strconv.ErrSyntax,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParserWithLayers(tt.layers, tt.opts...)
+			assert.NoError(t, err)
+			result := &target{}
+			err = parser.Parse(result)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantResult, result)
+		})
+	}
+}
+
+func TestParser_Parse_MapDefault(t *testing.T) {
+	type target struct {
+		Limits map[string]int `consulkv:"limits,default=a"`
+	}
+	parser, err := NewParserWithSource(NewMemorySource(nil))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, parser.Parse(&target{}), ErrUnhandledKind)
+}
+
+func TestParser_Watch_Map(t *testing.T) {
+	type target struct {
+		Limits map[string]int `consulkv:"limits/"`
+	}
+	source := NewMemorySource(map[string]string{
+		"limits/tenantA": "100",
+	})
+	parser, err := NewParserWithSource(source)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var values []map[string]int
+	result := &target{}
+	err = parser.Watch(ctx, result, func(event WatchEvent) {
+		assert.NoError(t, event.Err)
+		values = append(values, result.Limits)
+		switch len(values) {
+		case 1:
+			//Keys of subfolders aren't children of the folder.
+			source.Set("limits/tenantA/burst", "10")
+			source.Set("limits/tenantB", "200")
+		default:
+			cancel()
+		}
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []map[string]int{
+		{"tenantA": 100},
+		{"tenantA": 100, "tenantB": 200},
+	}, values)
+}
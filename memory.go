@@ -0,0 +1,177 @@
+package consulparser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+//MemorySource defines a WatchSource holding the pairs in memory, e.g. for tests and local development.
+//Every change bumps the index of the source and wakes up the blocked WatchList calls.
+//A MemorySource is safe for concurrent use.
+type MemorySource struct {
+	mutex   sync.RWMutex
+	pairs   map[string]*api.KVPair
+	index   uint64
+	changed chan struct{}
+}
+
+//exportEntry defines an entry of the file written by `consul kv export`.
+type exportEntry struct {
+	Key   string `json:"key"`
+	Flags uint64 `json:"flags"`
+	Value []byte `json:"value"`
+}
+
+//NewMemorySource returns the source holding the values indexed by their key.
+func NewMemorySource(values map[string]string) *MemorySource {
+	source := &MemorySource{
+		pairs:   make(map[string]*api.KVPair, len(values)),
+		index:   1,
+		changed: make(chan struct{}),
+	}
+	for key, value := range values {
+		source.pairs[key] = &api.KVPair{
+			Key:         key,
+			Value:       []byte(value),
+			CreateIndex: source.index,
+			ModifyIndex: source.index,
+		}
+	}
+	return source
+}
+
+//LoadExport returns the source holding the pairs of a `consul kv export` JSON document.
+func LoadExport(reader io.Reader) (source *MemorySource, err error) {
+	var entries []exportEntry
+	err = json.NewDecoder(reader).Decode(&entries)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrInvalidExport, err)
+		return
+	}
+	source = NewMemorySource(nil)
+	for _, entry := range entries {
+		source.pairs[entry.Key] = &api.KVPair{
+			Key:         entry.Key,
+			Flags:       entry.Flags,
+			Value:       entry.Value,
+			CreateIndex: source.index,
+			ModifyIndex: source.index,
+		}
+	}
+	return
+}
+
+//LoadExportFile returns the source holding the pairs of the file written by `consul kv export`.
+func LoadExportFile(path string) (source *MemorySource, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	source, err = LoadExport(file)
+	return
+}
+
+//Get returns the pair of the key, or nil when the key doesn't exist.
+func (source *MemorySource) Get(ctx context.Context, key string) (pair *api.KVPair, err error) {
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+	source.mutex.RLock()
+	defer source.mutex.RUnlock()
+	pair = source.pairs[key]
+	return
+}
+
+//List returns every pair whose key starts with the prefix, sorted by key like consul does.
+func (source *MemorySource) List(ctx context.Context, prefix string) (pairs api.KVPairs, err error) {
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+	source.mutex.RLock()
+	defer source.mutex.RUnlock()
+	pairs = source.list(prefix)
+	return
+}
+
+//WatchList is like List, but blocks until the index of the source is past waitIndex or the ctx is done.
+func (source *MemorySource) WatchList(ctx context.Context, prefix string, waitIndex uint64) (pairs api.KVPairs, index uint64, err error) {
+	for {
+		source.mutex.RLock()
+		changed := source.changed
+		index = source.index
+		if index > waitIndex {
+			pairs = source.list(prefix)
+		}
+		source.mutex.RUnlock()
+		if index > waitIndex {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			pairs, index, err = nil, 0, ctx.Err()
+			return
+		case <-changed:
+		}
+	}
+}
+
+//Set stores the value of the key.
+func (source *MemorySource) Set(key, value string) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	source.index++
+	pair := &api.KVPair{
+		Key:         key,
+		Value:       []byte(value),
+		CreateIndex: source.index,
+		ModifyIndex: source.index,
+	}
+	//The stored pair is replaced instead of mutated, since readers may still hold it.
+	if previous, ok := source.pairs[key]; ok {
+		pair.CreateIndex = previous.CreateIndex
+		pair.Flags = previous.Flags
+	}
+	source.pairs[key] = pair
+	source.notify()
+}
+
+//Delete removes the key, doing nothing when it doesn't exist.
+func (source *MemorySource) Delete(key string) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	if _, ok := source.pairs[key]; !ok {
+		return
+	}
+	source.index++
+	delete(source.pairs, key)
+	source.notify()
+}
+
+//notify wakes up the blocked WatchList calls, it must be called with the write lock held.
+func (source *MemorySource) notify() {
+	close(source.changed)
+	source.changed = make(chan struct{})
+}
+
+func (source *MemorySource) list(prefix string) (pairs api.KVPairs) {
+	for key, pair := range source.pairs {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, pair)
+		}
+	}
+	sort.Slice(pairs, func(first, second int) bool {
+		return pairs[first].Key < pairs[second].Key
+	})
+	return
+}
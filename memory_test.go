@@ -0,0 +1,148 @@
+package consulparser
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadExportFile(t *testing.T) {
+	type DBConfig struct {
+		Host string `consulkv:"host"`
+		Port int    `consulkv:"port"`
+	}
+	type target struct {
+		Name string   `consulkv:"app/name"`
+		DB   DBConfig `consulkv:"app/db/"`
+	}
+	tests := []struct {
+		name       string
+		load       func() (*MemorySource, error)
+		wantErr    error
+		wantResult *target
+	}{
+		{
+			name: "Export File",
+			load: func() (*MemorySource, error) {
+				return LoadExportFile("testdata/kv-export.json")
+			},
+			wantResult: &target{
+				Name: "billing",
+				DB: DBConfig{
+					Host: "localhost",
+					Port: 5432,
+				},
+			},
+		},
+		{
+			name: "Missing File",
+			load: func() (*MemorySource, error) {
+				return LoadExportFile("testdata/missing.json")
+			},
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name: "Invalid Export",
+			load: func() (*MemorySource, error) {
+				return LoadExport(strings.NewReader(`{"key": "app/name"}`))
+			},
+			wantErr: ErrInvalidExport,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := tt.load()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, source)
+				return
+			}
+			assert.NoError(t, err)
+			parser, err := NewParserWithSource(source)
+			assert.NoError(t, err)
+			result := &target{}
+			assert.NoError(t, parser.Parse(result))
+			assert.Equal(t, tt.wantResult, result)
+		})
+	}
+}
+
+func TestMemorySource(t *testing.T) {
+	source := NewMemorySource(map[string]string{
+		"app/db/host": "localhost",
+		"app/db/port": "5432",
+		"app/name":    "billing",
+	})
+	ctx := context.Background()
+	pair, err := source.Get(ctx, "app/name")
+	assert.NoError(t, err)
+	assert.Equal(t, "billing", string(pair.Value))
+	pair, err = source.Get(ctx, "app/missing")
+	assert.NoError(t, err)
+	assert.Nil(t, pair)
+	pairs, err := source.List(ctx, "app/db/")
+	assert.NoError(t, err)
+	var keys []string
+	for _, pair := range pairs {
+		keys = append(keys, pair.Key)
+	}
+	assert.Equal(t, []string{"app/db/host", "app/db/port"}, keys)
+	//The key is modified at a new index and keeps its create index.
+	source.Set("app/name", "payments")
+	pair, err = source.Get(ctx, "app/name")
+	assert.NoError(t, err)
+	assert.Equal(t, "payments", string(pair.Value))
+	assert.Equal(t, uint64(1), pair.CreateIndex)
+	assert.Equal(t, uint64(2), pair.ModifyIndex)
+	source.Delete("app/name")
+	source.Delete("app/missing")
+	pair, err = source.Get(ctx, "app/name")
+	assert.NoError(t, err)
+	assert.Nil(t, pair)
+	_, index, err := source.WatchList(ctx, "app/", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), index)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _, err = source.WatchList(canceled, "app/", index)
+	assert.Equal(t, context.Canceled, err)
+	_, err = source.Get(canceled, "app/name")
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestMemorySource_Watch(t *testing.T) {
+	type target struct {
+		Host string `consulkv:"app/db/host"`
+		Port int    `consulkv:"app/db/port"`
+	}
+	source := NewMemorySource(map[string]string{
+		"app/db/host": "localhost",
+		"app/db/port": "5432",
+	})
+	parser, err := NewParserWithSource(source)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var values []target
+	result := &target{}
+	err = parser.Watch(ctx, result, func(event WatchEvent) {
+		assert.NoError(t, event.Err)
+		values = append(values, *result)
+		switch len(values) {
+		case 1:
+			//Unrelated keys don't trigger a reload.
+			source.Set("app/db/other", "x")
+			source.Set("app/db/host", "db.local")
+		default:
+			cancel()
+		}
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []target{
+		{Host: "localhost", Port: 5432},
+		{Host: "db.local", Port: 5432},
+	}, values)
+}
@@ -0,0 +1,172 @@
+package consulparser
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+//Option defines the function to configure the Parser in NewParser.
+type Option func(parser *Parser) error
+
+//MissingKeyPolicy defines how the parser handles a tagged key that doesn't exist in consul.
+type MissingKeyPolicy int
+
+const (
+	//MissingKeyError fails the parse with an error wrapping ErrKeyNotFound.
+	MissingKeyError MissingKeyPolicy = iota
+	//MissingKeySkip leaves the field as it is, keeping any value set before parsing.
+	MissingKeySkip
+	//MissingKeyDefault resets the field to the zero value of its type.
+	MissingKeyDefault
+)
+
+//WithTimeLayout sets the layout used to parse time.Time fields.
+//The default layout is time.RFC3339.
+func WithTimeLayout(layout string) Option {
+	return func(parser *Parser) (err error) {
+		if layout == "" {
+			err = ErrEmptyLayout
+			return
+		}
+		parser.timeLayout = layout
+		return
+	}
+}
+
+//WithTagName sets the struct tag name that holds the consul key.
+//The default tag name is "consulkv".
+func WithTagName(name string) Option {
+	return func(parser *Parser) (err error) {
+		if name == "" {
+			err = ErrEmptyTagName
+			return
+		}
+		parser.tagName = name
+		return
+	}
+}
+
+//WithQueryOptions sets the query options used for every request of the LayerConsul layer to the consul server.
+//They aren't used by the other layers, e.g. of NewParserWithSource, whose sources hold their own options.
+func WithQueryOptions(queryOptions *api.QueryOptions) Option {
+	return func(parser *Parser) (err error) {
+		for index, layer := range parser.layers {
+			source, ok := layer.Source.(*consulSource)
+			if !ok || layer.Name != LayerConsul {
+				continue
+			}
+			//The source is replaced instead of mutated, since it may be shared with other parsers.
+			newSource := &consulSource{
+				kv:  source.kv,
+				txn: source.txn,
+			}
+			if queryOptions != nil {
+				copied := *queryOptions
+				newSource.queryOptions = &copied
+			}
+			parser.layers[index].Source = newSource
+		}
+		return
+	}
+}
+
+//WithPrefetch toggles the prefetch mode of the parser.
+//When enabled, Parse reads the keys of the target in a folder with a few recursive KV.List calls
+//on the common prefixes of the keys instead of one KV.Get per field. Keys without a folder are still read one by one.
+func WithPrefetch(enable bool) Option {
+	return func(parser *Parser) (err error) {
+		parser.prefetch = enable
+		return
+	}
+}
+
+//WithMissingKeyPolicy sets how the parser handles tagged keys that don't exist in consul.
+//The default policy is MissingKeyError.
+func WithMissingKeyPolicy(policy MissingKeyPolicy) Option {
+	return func(parser *Parser) (err error) {
+		switch policy {
+		case MissingKeyError, MissingKeySkip, MissingKeyDefault:
+			parser.missingKeyPolicy = policy
+		default:
+			err = ErrUnknownPolicy
+		}
+		return
+	}
+}
+
+//WithCollectErrors makes Parse continue through the whole target after a field fails.
+//Every failing field is then returned together in a MultiError.
+func WithCollectErrors(enable bool) Option {
+	return func(parser *Parser) (err error) {
+		parser.collectErrors = enable
+		return
+	}
+}
+
+//WithRootPrefix puts every key of the parser under the prefix, e.g. "production/billing/".
+//A slash is appended to the prefix when it doesn't end with one.
+func WithRootPrefix(prefix string) Option {
+	return func(parser *Parser) (err error) {
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		parser.rootPrefix = prefix
+		return
+	}
+}
+
+//WithKeyVariables sets the variables used by the key templates, e.g. `consulkv:"{{.Env}}/db/host"`.
+//A key that uses a variable which isn't set fails with ErrInvalidKeyTemplate.
+func WithKeyVariables(variables map[string]string) Option {
+	return func(parser *Parser) (err error) {
+		parser.keyVariables = make(map[string]string, len(variables))
+		for name, value := range variables {
+			parser.keyVariables[name] = value
+		}
+		return
+	}
+}
+
+//WithEnvOverrides makes the environment variables override the consul keys, e.g. for local debugging.
+//A field takes its variable from the env tag, e.g. `env:"DB_HOST"`, or else from its consul key
+//relative to the root prefix, uppercased with every other character than letters and digits
+//replaced by an underscore and put after the prefix, e.g. APP_DB_PRIMARY_HOST for db/primary-host.
+//An underscore is appended to the prefix when it doesn't end with one.
+//With an empty prefix only the env tags are honoured, since keys would map to bare names like PATH.
+//The overrides take precedence over every layer; use NewEnvSource to put the environment in a layer instead.
+//Variables that are unset or empty don't override anything.
+func WithEnvOverrides(prefix string) Option {
+	return func(parser *Parser) (err error) {
+		if prefix != "" && !strings.HasSuffix(prefix, "_") {
+			prefix += "_"
+		}
+		parser.envOverrides = true
+		parser.envPrefix = prefix
+		return
+	}
+}
+
+//WithConverter registers the function converting the raw values of the fields of the type,
+//like RegisterConverter.
+func WithConverter(typ reflect.Type, converter func(string) (interface{}, error)) Option {
+	return func(parser *Parser) (err error) {
+		err = parser.RegisterConverter(typ, converter)
+		return
+	}
+}
+
+func (parser *Parser) layout() string {
+	if parser.timeLayout == "" {
+		return defaultTimeLayout
+	}
+	return parser.timeLayout
+}
+
+func (parser *Parser) tag() string {
+	if parser.tagName == "" {
+		return defaultTagName
+	}
+	return parser.tagName
+}
@@ -0,0 +1,198 @@
+package consulparser
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewParser_Options(t *testing.T) {
+	generalClient, _ := api.NewClient(api.DefaultConfig())
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantParser func() *Parser
+		wantErr    error
+	}{
+		{
+			name: "All Options",
+			opts: []Option{
+				WithTimeLayout(time.RFC1123),
+				WithTagName("kv"),
+				WithQueryOptions(&api.QueryOptions{
+					Datacenter: "dc2",
+				}),
+				WithPrefetch(true),
+				WithMissingKeyPolicy(MissingKeySkip),
+				WithCollectErrors(true),
+				WithRootPrefix("production"),
+				WithKeyVariables(map[string]string{
+					"Env": "staging",
+				}),
+				WithEnvOverrides("APP"),
+			},
+			wantParser: func() *Parser {
+				return &Parser{
+					layers: []Layer{
+						{
+							Name: LayerConsul,
+							Source: &consulSource{
+								kv:  generalClient.KV(),
+								txn: generalClient.Txn(),
+								queryOptions: &api.QueryOptions{
+									Datacenter: "dc2",
+								},
+							},
+						},
+					},
+					timeLayout:       time.RFC1123,
+					tagName:          "kv",
+					prefetch:         true,
+					missingKeyPolicy: MissingKeySkip,
+					collectErrors:    true,
+					rootPrefix:       "production/",
+					keyVariables: map[string]string{
+						"Env": "staging",
+					},
+					envOverrides: true,
+					envPrefix:    "APP_",
+				}
+			},
+		},
+		{
+			name: "Empty Layout",
+			opts: []Option{
+				WithTimeLayout(""),
+			},
+			wantErr: ErrEmptyLayout,
+		},
+		{
+			name: "Unknown Missing Key Policy",
+			opts: []Option{
+				WithMissingKeyPolicy(MissingKeyPolicy(-1)),
+			},
+			wantErr: ErrUnknownPolicy,
+		},
+		{
+			name: "Empty Tag Name",
+			opts: []Option{
+				WithTagName(""),
+			},
+			wantErr: ErrEmptyTagName,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotParser, err := NewParser(generalClient, tt.opts...)
+			if err != tt.wantErr {
+				t.Errorf("NewParser() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr != nil {
+				assert.Nil(t, gotParser)
+				return
+			}
+			assert.EqualValues(t, tt.wantParser(), gotParser)
+		})
+	}
+}
+
+func TestParser_Parse_Options(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	const (
+		responseJSON = `[
+				{
+					"LockIndex": 0,
+					"Key": "%s",
+					"Flags": 0,
+					"Value": "%s",
+					"CreateIndex": 0,
+					"ModifyIndex": 0
+				}
+			]
+		`
+	)
+	timeResp := fmt.Sprintf(responseJSON, "time", base64.StdEncoding.EncodeToString([]byte("Fri, 01 Feb 2019 00:00:00 UTC")))
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/time?dc=dc2",
+		httpmock.NewStringResponder(http.StatusOK, timeResp),
+	)
+	hostResp := fmt.Sprintf(responseJSON, "production/staging/billing/db/host", base64.StdEncoding.EncodeToString([]byte("localhost")))
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/production/staging/billing/db/host",
+		httpmock.NewStringResponder(http.StatusOK, hostResp),
+	)
+	client, err := api.NewClient(&api.Config{
+		HttpClient: &http.Client{},
+	})
+	if err != nil {
+		t.Error("Failed to start the client!")
+	}
+	rfc1123Parser, err := NewParser(client,
+		WithTimeLayout(time.RFC1123),
+		WithTagName("kv"),
+		WithQueryOptions(&api.QueryOptions{
+			Datacenter: "dc2",
+		}),
+	)
+	if err != nil {
+		t.Errorf("Failed to create the parser: %s", err)
+	}
+	defaultParser, err := NewParser(client, WithTagName("kv"), WithQueryOptions(&api.QueryOptions{
+		Datacenter: "dc2",
+	}))
+	if err != nil {
+		t.Errorf("Failed to create the parser: %s", err)
+	}
+	type target struct {
+		Time time.Time `kv:"time"`
+	}
+	expected, err := time.Parse(time.RFC1123, "Fri, 01 Feb 2019 00:00:00 UTC")
+	if err != nil {
+		t.Errorf("Error in parsing the time value: %s", err)
+	}
+	//Parsers with different layouts run side by side without sharing the layout.
+	var wg sync.WaitGroup
+	for index := 0; index < 10; index++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			result := &target{}
+			assert.NoError(t, rfc1123Parser.Parse(result))
+			assert.Equal(t, expected, result.Time)
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Error(t, defaultParser.Parse(&target{}))
+		}()
+	}
+	wg.Wait()
+	//Keys are expanded with the variables and put under the root prefix.
+	prefixParser, err := NewParser(client,
+		WithRootPrefix("production"),
+		WithKeyVariables(map[string]string{
+			"Env":     "staging",
+			"Service": "billing",
+		}),
+	)
+	if err != nil {
+		t.Errorf("Failed to create the parser: %s", err)
+	}
+	database := &struct {
+		Database struct {
+			Host string `consulkv:"host"`
+		} `consulkv:"{{.Env}}/{{.Service}}/db/"`
+	}{}
+	assert.NoError(t, prefixParser.Parse(database))
+	assert.Equal(t, "localhost", database.Database.Host)
+}
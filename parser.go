@@ -1,8 +1,10 @@
 package consulparser
 
 import (
+	"context"
 	"reflect"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/consul/api"
@@ -10,43 +12,144 @@ import (
 
 type ParserIface interface {
 	Parse(interface{}) error
+	ParseContext(context.Context, interface{}) error
+	ParseSnapshot(interface{}) (uint64, error)
+	ParseSnapshotContext(context.Context, interface{}) (uint64, error)
+	Watch(context.Context, interface{}, func(WatchEvent)) error
+	Diff(interface{}, interface{}) ([]Change, error)
+	ParseReport(context.Context, interface{}) (Report, error)
+	RegisterConverter(reflect.Type, func(string) (interface{}, error)) error
 }
 
 //Parser defines struct for the parser API.
+//All settings live on the Parser instance, so parsers with different options don't affect each other.
+//A Parser is safe for concurrent Parse calls from multiple goroutines,
+//as long as its setters aren't called at the same time.
 type Parser struct {
-	consulKV *api.KV
+	layers           []Layer
+	timeLayout       string
+	tagName          string
+	prefetch         bool
+	missingKeyPolicy MissingKeyPolicy
+	collectErrors    bool
+	rootPrefix       string
+	keyVariables     map[string]string
+	envOverrides     bool
+	envPrefix        string
+	converters       map[reflect.Type]func(string) (interface{}, error)
+	//templates caches the parsed key templates indexed by their key.
+	templates sync.Map
 }
 
 const (
-	keyTag   = "consulkv"
-	timeType = "time.Time"
+	defaultTagName    = "consulkv"
+	defaultTimeLayout = time.RFC3339
+	timeType          = "time.Time"
+	durationType      = "time.Duration"
 )
 
-var (
-	timeLayout = time.RFC3339
-)
-
-//NewParser initialize a new parser with the supplied consul client.
-func NewParser(client *api.Client) (parser ParserIface, err error) {
+//NewParser initialize a new parser with the supplied consul client and options.
+func NewParser(client *api.Client, opts ...Option) (parser ParserIface, err error) {
 	if client == nil {
 		return nil, ErrNilClient
 	}
-	parser = &Parser{
-		consulKV: client.KV(),
+	newParser := &Parser{
+		layers: []Layer{
+			{
+				Name: LayerConsul,
+				Source: &consulSource{
+					kv:  client.KV(),
+					txn: client.Txn(),
+				},
+			},
+		},
+	}
+	for _, opt := range opts {
+		err = opt(newParser)
+		if err != nil {
+			return
+		}
 	}
+	parser = newParser
+	return
+}
+
+//NewParserWithSource initialize a new parser reading the tagged keys from the source with the options.
+//ParseSnapshot is only supported by the consul sources returned by NewConsulSource,
+//and Watch by the sources implementing WatchSource.
+func NewParserWithSource(source Source, opts ...Option) (parser ParserIface, err error) {
+	if source == nil {
+		return nil, ErrNilSource
+	}
+	newParser := &Parser{
+		layers: []Layer{
+			{
+				Name:   LayerSource,
+				Source: source,
+			},
+		},
+	}
+	for _, opt := range opts {
+		err = opt(newParser)
+		if err != nil {
+			return
+		}
+	}
+	parser = newParser
 	return
 }
 
 //Parse gives the value to the target from the consul server.
 //Parse uses the struct tag to identify the value of the key.
+//The target is left untouched when Parse returns an error.
 func (parser *Parser) Parse(target interface{}) (err error) {
+	err = parser.ParseContext(context.Background(), target)
+	return
+}
+
+//ParseContext is like Parse, but every request to the consul server is bound to the ctx.
+//When the ctx is done, ParseContext returns the error of the ctx and the target is left untouched.
+func (parser *Parser) ParseContext(ctx context.Context, target interface{}) (err error) {
+	err = parser.parseTarget(&parseState{ctx: ctx}, target)
+	return
+}
+
+//parseTarget loads the pairs needed by the state and assigns them to a copy of the target.
+//The copy is only written back to the target when every field is assigned successfully.
+func (parser *Parser) parseTarget(state *parseState, target interface{}) (err error) {
 	valueStruct := reflect.ValueOf(target)
 	if valueStruct.Kind() != reflect.Ptr || !valueStruct.IsValid() {
 		return ErrNonPointerType
 	}
+	elemVal := parser.getRecursivePointerVal(valueStruct)
+	//A nil pointer, at any depth, has nothing to assign to.
+	if !elemVal.IsValid() {
+		return ErrNonPointerType
+	}
+	switch {
+	case state.snapshot:
+		var pairs map[string]*api.KVPair
+		pairs, state.index, err = parser.snapshotPairs(state, elemVal)
+		state.pairs = []map[string]*api.KVPair{pairs}
+	case parser.prefetch:
+		state.pairs, err = parser.prefetchPairs(state, elemVal)
+	}
+	if err != nil {
+		return
+	}
 	//Start as empty value first.
 	//This is acceptable to check the target struct first.
-	err = parser.assign(parser.getRecursivePointerVal(valueStruct), "")
+	copyVal := reflect.New(elemVal.Type()).Elem()
+	copyVal.Set(elemVal)
+	err = parser.assign(state, copyVal, scope{}, "")
+	if err != nil {
+		return
+	}
+	if len(state.errs) > 0 {
+		err = &MultiError{Errors: state.errs}
+		return
+	}
+	elemVal.Set(copyVal)
 	return
 }
 
@@ -58,43 +161,119 @@ func (parser *Parser) getRecursivePointerVal(val reflect.Value) (elemVal reflect
 	return
 }
 
-func (parser *Parser) parse(v reflect.Value) (err error) {
-	var value string
+func (parser *Parser) parse(state *parseState, v reflect.Value, parent scope) (err error) {
 	typeV := v.Type()
 	for index := 0; index < v.NumField(); index++ {
 		field := v.Field(index)
 		if !field.CanSet() || !field.IsValid() {
 			continue
 		}
-		consulKey := typeV.Field(index).Tag.Get(keyTag)
-		value, err = parser.getValue(consulKey)
-		if err != nil {
-			return
+		var (
+			value       string
+			found       bool
+			fieldReport FieldReport
+		)
+		tagOpts := parseTag(typeV.Field(index).Tag.Get(parser.tag()))
+		fieldScope := parent.field(typeV.Field(index).Name)
+		fieldScope.options = tagOpts
+		consulKey := ""
+		if tagOpts.format == "" && parser.isNestedStruct(indirectType(field.Type())) {
+			//The key of a struct field is the prefix of its children, the struct itself has no value.
+			fieldScope.prefix = resolvePrefix(parent.prefix, tagOpts.key)
+			value, found, fieldReport, err = "", true, FieldReport{}, nil
+		} else {
+			consulKey = parser.resolveFieldKey(parent.prefix, tagOpts, field.Type())
+			consulKey, err = parser.expandKey(consulKey)
+			switch {
+			case err != nil:
+			case tagOpts.format == "" && parser.isFolder(indirectType(field.Type())):
+				//The structs of the subfolders are parsed with the folder as prefix.
+				value = ""
+				fieldScope.prefix = resolvePrefix(parent.prefix, tagOpts.key)
+				fieldScope.children, fieldScope.subfolders, fieldReport, err = parser.getChildren(state, consulKey)
+				found = consulKey == "" || len(fieldScope.children) > 0 || len(fieldScope.subfolders) > 0
+			default:
+				value, found, fieldReport, err = parser.getValue(state, typeV.Field(index).Tag.Get(envTagName), consulKey)
+			}
+		}
+		if err == nil && !found {
+			switch {
+			case tagOpts.required:
+				err = ErrKeyNotFound
+			case tagOpts.hasDefault:
+				value = tagOpts.defaultValue
+				fieldReport.Layer, fieldReport.Default = LayerDefault, true
+			case parser.missingKeyPolicy == MissingKeySkip:
+				fieldReport.Missing = true
+				state.record(fieldScope.path, fieldReport)
+				continue
+			case parser.missingKeyPolicy == MissingKeyDefault:
+				field.Set(reflect.Zero(field.Type()))
+				fieldReport.Missing = true
+				state.record(fieldScope.path, fieldReport)
+				continue
+			default:
+				err = ErrKeyNotFound
+			}
+		}
+		if err == nil {
+			err = parser.assign(state, field, fieldScope, value)
+		}
+		if err == nil {
+			fieldReport.Empty = value == "" && (tagOpts.format != "" || !parser.isFolder(indirectType(field.Type())))
+			state.record(fieldScope.path, fieldReport)
 		}
-		err = parser.assign(field, value)
 		if err != nil {
-			return
+			err = parser.fail(state, newFieldError(fieldScope.path, consulKey, value, field.Type(), err))
+			if err != nil {
+				return
+			}
 		}
 	}
 	return
 }
 
-func (parser *Parser) assign(val reflect.Value, value string) (err error) {
+//fail records the field error when the parser collects errors and returns nil to keep parsing.
+//Otherwise, or when the context of the state is done, the error is returned to stop the parse.
+func (parser *Parser) fail(state *parseState, err error) error {
+	if !parser.collectErrors || state.ctx.Err() != nil {
+		return err
+	}
+	state.errs = append(state.errs, err)
+	return nil
+}
+
+func (parser *Parser) assign(state *parseState, val reflect.Value, sc scope, value string) (err error) {
+	if sc.options.format != "" {
+		err = decode(val, value, sc.options.format)
+		return
+	}
+	handled, err := parser.convert(val, value)
+	if handled || err != nil {
+		return
+	}
+	handled, err = parser.unmarshal(val, value)
+	if handled || err != nil {
+		return
+	}
 	switch val.Kind() {
 	case reflect.Ptr:
-		err = parser.assignPointer(val, value)
+		err = parser.assignPointer(state, val, sc, value)
 	default:
-		err = parser.assignNonPointer(val, value)
+		err = parser.assignNonPointer(state, val, sc, value)
 	}
 	return
 }
 
-func (parser *Parser) assignPointer(val reflect.Value, value string) (err error) {
+func (parser *Parser) assignPointer(state *parseState, val reflect.Value, sc scope, value string) (err error) {
 	var tempVal reflect.Value
 	switch val.Type().Elem().Kind() {
 	case reflect.Ptr:
 		tempVal = reflect.New(val.Type().Elem())
-		err = parser.assignPointer(tempVal.Elem(), value)
+		if !val.IsNil() {
+			tempVal.Elem().Set(val.Elem())
+		}
+		err = parser.assign(state, tempVal.Elem(), sc, value)
 		if err != nil {
 			return
 		}
@@ -105,14 +284,19 @@ func (parser *Parser) assignPointer(val reflect.Value, value string) (err error)
 			}
 			var timeVal time.Time
 			//Using time.RFC3339 as the layout
-			timeVal, err = time.Parse(timeLayout, value)
+			timeVal, err = time.Parse(parser.layout(), value)
 			if err != nil {
 				return
 			}
 			tempVal = reflect.ValueOf(&timeVal)
 		} else {
+			//The new struct starts as a copy of the previous one, so the skipped fields keep their value
+			//without mutating the struct of a previous parse.
 			tempVal = reflect.New(val.Type().Elem())
-			err = parser.parse(tempVal.Elem())
+			if !val.IsNil() {
+				tempVal.Elem().Set(val.Elem())
+			}
+			err = parser.parse(state, tempVal.Elem(), sc)
 			if err != nil {
 				return
 			}
@@ -123,12 +307,37 @@ func (parser *Parser) assignPointer(val reflect.Value, value string) (err error)
 		}
 		tempVal = reflect.New(val.Type().Elem())
 		tempVal.Elem().Set(reflect.ValueOf(value))
+	case reflect.Slice, reflect.Array:
+		if value == "" && sc.subfolders == nil {
+			return
+		}
+		tempVal = reflect.New(val.Type().Elem())
+		err = parser.assignList(state, tempVal.Elem(), sc, value)
+		if err != nil {
+			return
+		}
+	case reflect.Map:
+		if value == "" && sc.children == nil && sc.subfolders == nil {
+			return
+		}
+		tempVal = reflect.New(val.Type().Elem())
+		err = parser.assignMap(state, tempVal.Elem(), sc, value)
+		if err != nil {
+			return
+		}
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if value == "" {
 			return
 		}
 		var temp int64
-		temp, err = strconv.ParseInt(value, 10, 64)
+		if val.Type().Elem().String() == durationType {
+			var duration time.Duration
+			//Durations are written like 30s or 5m.
+			duration, err = time.ParseDuration(value)
+			temp = int64(duration)
+		} else {
+			temp, err = strconv.ParseInt(value, 10, 64)
+		}
 		if err != nil {
 			return
 		}
@@ -137,7 +346,7 @@ func (parser *Parser) assignPointer(val reflect.Value, value string) (err error)
 			err = ErrOverflowSet
 			return
 		}
-		tempVal.Elem().Set(reflect.ValueOf(temp))
+		tempVal.Elem().SetInt(temp)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 		if value == "" {
 			return
@@ -152,7 +361,7 @@ func (parser *Parser) assignPointer(val reflect.Value, value string) (err error)
 			err = ErrOverflowSet
 			return
 		}
-		tempVal.Elem().Set(reflect.ValueOf(temp))
+		tempVal.Elem().SetUint(temp)
 	case reflect.Float32, reflect.Float64:
 		if value == "" {
 			return
@@ -167,7 +376,7 @@ func (parser *Parser) assignPointer(val reflect.Value, value string) (err error)
 			err = ErrOverflowSet
 			return
 		}
-		tempVal.Elem().Set(reflect.ValueOf(temp))
+		tempVal.Elem().SetFloat(temp)
 	case reflect.Bool:
 		if value == "" {
 			return
@@ -178,7 +387,7 @@ func (parser *Parser) assignPointer(val reflect.Value, value string) (err error)
 			return
 		}
 		tempVal = reflect.New(val.Type().Elem())
-		tempVal.Elem().Set(reflect.ValueOf(temp))
+		tempVal.Elem().SetBool(temp)
 	default:
 		err = ErrUnhandledKind
 		return
@@ -187,7 +396,7 @@ func (parser *Parser) assignPointer(val reflect.Value, value string) (err error)
 	return
 }
 
-func (parser *Parser) assignNonPointer(val reflect.Value, value string) (err error) {
+func (parser *Parser) assignNonPointer(state *parseState, val reflect.Value, sc scope, value string) (err error) {
 	switch val.Kind() {
 	case reflect.Struct:
 		if val.Type().String() == timeType {
@@ -196,25 +405,36 @@ func (parser *Parser) assignNonPointer(val reflect.Value, value string) (err err
 			}
 			var timeVal time.Time
 			//Using time.RFC3339 layout only
-			timeVal, err = time.Parse(timeLayout, value)
+			timeVal, err = time.Parse(parser.layout(), value)
 			if err != nil {
 				return
 			}
 			val.Set(reflect.ValueOf(timeVal))
 		} else {
-			err = parser.parse(val)
+			err = parser.parse(state, val, sc)
 		}
 	case reflect.Interface, reflect.String:
 		if value == "" {
 			return
 		}
 		val.Set(reflect.ValueOf(value))
+	case reflect.Slice, reflect.Array:
+		err = parser.assignList(state, val, sc, value)
+	case reflect.Map:
+		err = parser.assignMap(state, val, sc, value)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if value == "" {
 			return
 		}
 		var temp int64
-		temp, err = strconv.ParseInt(value, 10, 64)
+		if val.Type().String() == durationType {
+			var duration time.Duration
+			//Durations are written like 30s or 5m.
+			duration, err = time.ParseDuration(value)
+			temp = int64(duration)
+		} else {
+			temp, err = strconv.ParseInt(value, 10, 64)
+		}
 		if err != nil {
 			return
 		}
@@ -267,23 +487,53 @@ func (parser *Parser) assignNonPointer(val reflect.Value, value string) (err err
 	return
 }
 
-func (parser *Parser) getValue(consulKey string) (value string, err error) {
+//getValue reads the value of the consul key from the environment variable overriding it,
+//or else from the layers of the parser, starting with the highest precedence.
+//The report tells where the value comes from.
+//Fields without a key nor an environment variable are always found with an empty value.
+func (parser *Parser) getValue(state *parseState, envTag, consulKey string) (value string, found bool, fieldReport FieldReport, err error) {
+	fieldReport.Key = consulKey
+	value, found = parser.lookupEnv(envTag, consulKey)
+	if found {
+		fieldReport.Layer = LayerEnv
+		return
+	}
 	if consulKey == "" {
+		found = true
 		return
 	}
-	pair, _, err := parser.consulKV.Get(consulKey, nil)
+	err = state.ctx.Err()
 	if err != nil {
 		return
 	}
-	value = string(pair.Value)
+	layers := parser.layers
+	for index := len(layers) - 1; index >= 0; index-- {
+		var pair *api.KVPair
+		if state.pairs != nil {
+			pair = state.pairs[index][consulKey]
+		} else {
+			pair, err = layers[index].Source.Get(state.ctx, consulKey)
+			if err != nil {
+				return
+			}
+		}
+		if pair != nil {
+			value, found = string(pair.Value), true
+			fieldReport.Layer, fieldReport.ModifyIndex = layers[index].Name, pair.ModifyIndex
+			return
+		}
+	}
 	return
 }
 
+//SetTimeLayout sets the layout used to parse time.Time fields of this parser.
+//
+//Deprecated: use WithTimeLayout in NewParser, SetTimeLayout must not be called concurrently with Parse.
 func (parser *Parser) SetTimeLayout(layout string) (err error) {
 	if layout == "" {
 		err = ErrEmptyLayout
 		return
 	}
-	timeLayout = layout
+	parser.timeLayout = layout
 	return
 }
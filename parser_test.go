@@ -1,10 +1,13 @@
 package consulparser
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
+	"reflect"
 	"strconv"
 	"testing"
 	"time"
@@ -14,6 +17,18 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+//kvLayers returns the consul layer reading from the KV API, like the one of NewParser.
+func kvLayers(kv *api.KV) []Layer {
+	return []Layer{
+		{
+			Name: LayerConsul,
+			Source: &consulSource{
+				kv: kv,
+			},
+		},
+	}
+}
+
 func TestNewParser(t *testing.T) {
 	type args struct {
 		client func() *api.Client
@@ -34,7 +49,15 @@ func TestNewParser(t *testing.T) {
 			},
 			wantParser: func() ParserIface {
 				parser := &Parser{
-					consulKV: generalClient.KV(),
+					layers: []Layer{
+						{
+							Name: LayerConsul,
+							Source: &consulSource{
+								kv:  generalClient.KV(),
+								txn: generalClient.Txn(),
+							},
+						},
+					},
 				}
 				return parser
 			},
@@ -81,7 +104,7 @@ func TestParser_Parse(t *testing.T) {
 			]
 		`
 	)
-	// Init response mock for consul client
+	//Init response mock for consul client
 	stringResp := fmt.Sprintf(responseJSON, "string", base64.StdEncoding.EncodeToString([]byte("hello")))
 	intResp := fmt.Sprintf(responseJSON, "integer", base64.StdEncoding.EncodeToString([]byte("-10")))
 	floatResp := fmt.Sprintf(responseJSON, "float", base64.StdEncoding.EncodeToString([]byte("10.0")))
@@ -518,9 +541,7 @@ func TestParser_Parse(t *testing.T) {
 					UnsignedInteger uint64      `consulkv:"unsignedinteger"`
 					Boolean         bool        `consulkv:"boolean"`
 					Interface       interface{} `consulkv:"string"`
-				}{
-					Text: "hello",
-				}
+				}{}
 			},
 		},
 		{
@@ -557,10 +578,7 @@ func TestParser_Parse(t *testing.T) {
 					UnsignedInteger uint64      `consulkv:"unsignedinteger"`
 					Boolean         bool        `consulkv:"boolean"`
 					Interface       interface{} `consulkv:"string"`
-				}{
-					Text:    "hello",
-					Integer: -10,
-				}
+				}{}
 			},
 		},
 		{
@@ -597,11 +615,7 @@ func TestParser_Parse(t *testing.T) {
 					UnsignedInteger uint64      `consulkv:"string"`
 					Boolean         bool        `consulkv:"boolean"`
 					Interface       interface{} `consulkv:"string"`
-				}{
-					Text:    "hello",
-					Integer: -10,
-					Float:   10.0,
-				}
+				}{}
 			},
 		},
 		{
@@ -638,12 +652,7 @@ func TestParser_Parse(t *testing.T) {
 					UnsignedInteger uint64      `consulkv:"unsignedinteger"`
 					Boolean         bool        `consulkv:"string"`
 					Interface       interface{} `consulkv:"string"`
-				}{
-					Text:            "hello",
-					Integer:         -10,
-					Float:           10.0,
-					UnsignedInteger: 1000,
-				}
+				}{}
 			},
 		},
 		{
@@ -1144,7 +1153,7 @@ func TestParser_Parse(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			parser := &Parser{
-				consulKV: tt.fields.consulKV(),
+				layers: kvLayers(tt.fields.consulKV()),
 			}
 			target := tt.args().target
 			if err := parser.Parse(target); (err != nil) != tt.wantErr {
@@ -1155,6 +1164,644 @@ func TestParser_Parse(t *testing.T) {
 	}
 }
 
+func TestParser_Parse_MissingKey(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	const (
+		responseJSON = `[
+				{
+					"LockIndex": 0,
+					"Key": "%s",
+					"Flags": 0,
+					"Value": "%s",
+					"CreateIndex": 0,
+					"ModifyIndex": 0
+				}
+			]
+		`
+	)
+	stringResp := fmt.Sprintf(responseJSON, "string", base64.StdEncoding.EncodeToString([]byte("hello")))
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/string",
+		httpmock.NewStringResponder(http.StatusOK, stringResp),
+	)
+	//Consul answers a missing key with 404 and an empty body.
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/missing",
+		httpmock.NewStringResponder(http.StatusNotFound, ""),
+	)
+	type Nested struct {
+		Port int `consulkv:"missing"`
+	}
+	type target struct {
+		String string `consulkv:"string"`
+		Nested Nested
+	}
+	tests := []struct {
+		name         string
+		policy       MissingKeyPolicy
+		args         func() interface{}
+		wantErr      error
+		wantErrText  string
+		expectResult func() interface{}
+	}{
+		{
+			name:   "Error Policy",
+			policy: MissingKeyError,
+			args: func() interface{} {
+				return &target{}
+			},
+			wantErr:     ErrKeyNotFound,
+			wantErrText: `field Nested.Port with key "missing" of kind int: key is not found in consul`,
+			expectResult: func() interface{} {
+				return &target{}
+			},
+		},
+		{
+			name:   "Skip Policy",
+			policy: MissingKeySkip,
+			args: func() interface{} {
+				return &target{
+					Nested: Nested{
+						Port: 8080,
+					},
+				}
+			},
+			expectResult: func() interface{} {
+				return &target{
+					String: "hello",
+					Nested: Nested{
+						Port: 8080,
+					},
+				}
+			},
+		},
+		{
+			name:   "Required Key with Skip Policy",
+			policy: MissingKeySkip,
+			args: func() interface{} {
+				return &struct {
+					Port int `consulkv:"missing,required"`
+				}{}
+			},
+			wantErr:     ErrKeyNotFound,
+			wantErrText: `field Port with key "missing" of kind int: key is not found in consul`,
+			expectResult: func() interface{} {
+				return &struct {
+					Port int `consulkv:"missing,required"`
+				}{}
+			},
+		},
+		{
+			name:   "Default Tag Value with Error Policy",
+			policy: MissingKeyError,
+			args: func() interface{} {
+				return &struct {
+					String  string `consulkv:"string,default=world"`
+					Port    int    `consulkv:"missing,default=5432"`
+					Pointer *uint8 `consulkv:"missing,default=8"`
+				}{}
+			},
+			expectResult: func() interface{} {
+				pointer := uint8(8)
+				return &struct {
+					String  string `consulkv:"string,default=world"`
+					Port    int    `consulkv:"missing,default=5432"`
+					Pointer *uint8 `consulkv:"missing,default=8"`
+				}{
+					String:  "hello",
+					Port:    5432,
+					Pointer: &pointer,
+				}
+			},
+		},
+		{
+			name:   "Invalid Default Tag Value",
+			policy: MissingKeyError,
+			args: func() interface{} {
+				return &struct {
+					Port int `consulkv:"missing,default=abc"`
+				}{}
+			},
+			wantErr: strconv.ErrSyntax,
+			expectResult: func() interface{} {
+				return &struct {
+					Port int `consulkv:"missing,default=abc"`
+				}{}
+			},
+		},
+		{
+			name:   "Default Policy",
+			policy: MissingKeyDefault,
+			args: func() interface{} {
+				return &target{
+					Nested: Nested{
+						Port: 8080,
+					},
+				}
+			},
+			expectResult: func() interface{} {
+				return &target{
+					String: "hello",
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := api.NewClient(&api.Config{
+				HttpClient: &http.Client{},
+			})
+			if err != nil {
+				t.Error("Failed to start the client!")
+			}
+			parser := &Parser{
+				layers:           kvLayers(client.KV()),
+				missingKeyPolicy: tt.policy,
+			}
+			target := tt.args()
+			err = parser.Parse(target)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErrText != "" {
+				assert.EqualError(t, err, tt.wantErrText)
+			}
+			assert.EqualValues(t, tt.expectResult(), target)
+		})
+	}
+}
+
+func TestParser_Parse_MissingKeySkipPointer(t *testing.T) {
+	type database struct {
+		Host string `consulkv:"host"`
+		Port string `consulkv:"port"`
+	}
+	type target struct {
+		DB      *database  `consulkv:"db/"`
+		Replica **database `consulkv:"replica/"`
+	}
+	parser, err := NewParserWithSource(NewMemorySource(map[string]string{
+		"db/host":      "primary.internal",
+		"replica/host": "replica.internal",
+	}), WithMissingKeyPolicy(MissingKeySkip))
+	assert.NoError(t, err)
+	previous := &database{Port: "5432"}
+	replica := &database{Port: "5433"}
+	result := &target{
+		DB:      previous,
+		Replica: &replica,
+	}
+	assert.NoError(t, parser.Parse(result))
+	assert.Equal(t, &database{Host: "primary.internal", Port: "5432"}, result.DB)
+	assert.Equal(t, &database{Host: "replica.internal", Port: "5433"}, *result.Replica)
+	//The structs of the previous parse are copied instead of mutated.
+	assert.Equal(t, &database{Port: "5432"}, previous)
+	assert.Equal(t, &database{Port: "5433"}, replica)
+}
+
+func TestParser_Parse_FieldError(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	const (
+		responseJSON = `[
+				{
+					"LockIndex": 0,
+					"Key": "%s",
+					"Flags": 0,
+					"Value": "%s",
+					"CreateIndex": 0,
+					"ModifyIndex": 0
+				}
+			]
+		`
+	)
+	badPortResp := fmt.Sprintf(responseJSON, "db/port", base64.StdEncoding.EncodeToString([]byte("abc")))
+	overflowResp := fmt.Sprintf(responseJSON, "db/pool", base64.StdEncoding.EncodeToString([]byte("300")))
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/db/port",
+		httpmock.NewStringResponder(http.StatusOK, badPortResp),
+	)
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/db/pool",
+		httpmock.NewStringResponder(http.StatusOK, overflowResp),
+	)
+	tests := []struct {
+		name      string
+		args      func() interface{}
+		wantErr   error
+		wantField *FieldError
+	}{
+		{
+			name: "Unparsable Value in Nested Struct",
+			args: func() interface{} {
+				return &struct {
+					Database struct {
+						Primary struct {
+							Port int `consulkv:"db/port"`
+						}
+					}
+				}{}
+			},
+			wantErr: strconv.ErrSyntax,
+			wantField: &FieldError{
+				Path:  "Database.Primary.Port",
+				Key:   "db/port",
+				Value: "abc",
+				Kind:  reflect.Int,
+			},
+		},
+		{
+			name: "Overflowing Value in Pointer Struct",
+			args: func() interface{} {
+				return &struct {
+					Database *struct {
+						Pool *int8 `consulkv:"db/pool"`
+					}
+				}{}
+			},
+			wantErr: ErrOverflowSet,
+			wantField: &FieldError{
+				Path:  "Database.Pool",
+				Key:   "db/pool",
+				Value: "300",
+				Kind:  reflect.Int8,
+			},
+		},
+		{
+			name: "Unhandled Kind",
+			args: func() interface{} {
+				return &struct {
+					Channel chan int `consulkv:"db/port"`
+				}{}
+			},
+			wantErr: ErrUnhandledKind,
+			wantField: &FieldError{
+				Path:  "Channel",
+				Key:   "db/port",
+				Value: "abc",
+				Kind:  reflect.Chan,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := api.NewClient(&api.Config{
+				HttpClient: &http.Client{},
+			})
+			if err != nil {
+				t.Error("Failed to start the client!")
+			}
+			parser := &Parser{
+				layers: kvLayers(client.KV()),
+			}
+			err = parser.Parse(tt.args())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("Parser.Parse() error = %v, want FieldError", err)
+			}
+			fieldErr.Err = nil
+			assert.Equal(t, tt.wantField, fieldErr)
+		})
+	}
+}
+
+func TestParser_Parse_CollectErrors(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	const (
+		responseJSON = `[
+				{
+					"LockIndex": 0,
+					"Key": "%s",
+					"Flags": 0,
+					"Value": "%s",
+					"CreateIndex": 0,
+					"ModifyIndex": 0
+				}
+			]
+		`
+	)
+	stringResp := fmt.Sprintf(responseJSON, "string", base64.StdEncoding.EncodeToString([]byte("hello")))
+	overflowResp := fmt.Sprintf(responseJSON, "overflowint", base64.StdEncoding.EncodeToString([]byte("300")))
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/string",
+		httpmock.NewStringResponder(http.StatusOK, stringResp),
+	)
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/overflowint",
+		httpmock.NewStringResponder(http.StatusOK, overflowResp),
+	)
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/missing",
+		httpmock.NewStringResponder(http.StatusNotFound, ""),
+	)
+	type target struct {
+		String   string `consulkv:"string"`
+		Missing  string `consulkv:"missing"`
+		Integer  int    `consulkv:"string"`
+		Overflow struct {
+			Value int8 `consulkv:"overflowint"`
+		}
+	}
+	tests := []struct {
+		name          string
+		collectErrors bool
+		wantErrs      []error
+		wantPaths     []string
+	}{
+		{
+			name:          "Stop at the First Error",
+			collectErrors: false,
+			wantErrs:      []error{ErrKeyNotFound},
+			wantPaths:     []string{"Missing"},
+		},
+		{
+			name:          "Collect Every Error",
+			collectErrors: true,
+			wantErrs:      []error{ErrKeyNotFound, strconv.ErrSyntax, ErrOverflowSet},
+			wantPaths:     []string{"Missing", "Integer", "Overflow.Value"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := api.NewClient(&api.Config{
+				HttpClient: &http.Client{},
+			})
+			if err != nil {
+				t.Error("Failed to start the client!")
+			}
+			parser := &Parser{
+				layers:        kvLayers(client.KV()),
+				collectErrors: tt.collectErrors,
+			}
+			result := &target{}
+			err = parser.Parse(result)
+			for _, wantErr := range tt.wantErrs {
+				assert.True(t, errors.Is(err, wantErr), "Parser.Parse() error = %v, wantErr %v", err, wantErr)
+			}
+			var paths []string
+			var multiErr *MultiError
+			if errors.As(err, &multiErr) {
+				for _, fieldErr := range multiErr.Errors {
+					paths = append(paths, fieldErr.(*FieldError).Path)
+				}
+			} else {
+				var fieldErr *FieldError
+				if errors.As(err, &fieldErr) {
+					paths = append(paths, fieldErr.Path)
+				}
+			}
+			assert.Equal(t, tt.wantPaths, paths)
+			assert.Equal(t, &target{}, result)
+		})
+	}
+}
+
+func TestParser_Parse_NestedPrefix(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	const (
+		responseJSON = `[
+				{
+					"LockIndex": 0,
+					"Key": "%s",
+					"Flags": 0,
+					"Value": "%s",
+					"CreateIndex": 0,
+					"ModifyIndex": 0
+				}
+			]
+		`
+	)
+	values := map[string]string{
+		"database/primary/host": "primary.local",
+		"database/primary/port": "5432",
+		"database/replica/host": "replica.local",
+		"database/replica/port": "5433",
+		"timeout":               "30",
+	}
+	for key, value := range values {
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			"http://127.0.0.1:8500/v1/kv/"+key,
+			httpmock.NewStringResponder(http.StatusOK, fmt.Sprintf(responseJSON, key, base64.StdEncoding.EncodeToString([]byte(value)))),
+		)
+	}
+	type DBConfig struct {
+		Host    string `consulkv:"host"`
+		Port    int    `consulkv:"port"`
+		Timeout int    `consulkv:"/timeout"`
+	}
+	type Database struct {
+		Primary DBConfig  `consulkv:"primary/"`
+		Replica *DBConfig `consulkv:"replica"`
+	}
+	tests := []struct {
+		name         string
+		args         func() interface{}
+		expectResult func() interface{}
+	}{
+		{
+			name: "Prefix from Struct Hierarchy",
+			args: func() interface{} {
+				return &struct {
+					Database Database `consulkv:"database/"`
+				}{}
+			},
+			expectResult: func() interface{} {
+				return &struct {
+					Database Database `consulkv:"database/"`
+				}{
+					Database: Database{
+						Primary: DBConfig{
+							Host:    "primary.local",
+							Port:    5432,
+							Timeout: 30,
+						},
+						Replica: &DBConfig{
+							Host:    "replica.local",
+							Port:    5433,
+							Timeout: 30,
+						},
+					},
+				}
+			},
+		},
+		{
+			name: "Absolute Prefix on Struct Field",
+			args: func() interface{} {
+				return &struct {
+					Other struct {
+						Primary DBConfig `consulkv:"/database/primary"`
+					} `consulkv:"other/"`
+				}{}
+			},
+			expectResult: func() interface{} {
+				return &struct {
+					Other struct {
+						Primary DBConfig `consulkv:"/database/primary"`
+					} `consulkv:"other/"`
+				}{
+					Other: struct {
+						Primary DBConfig `consulkv:"/database/primary"`
+					}{
+						Primary: DBConfig{
+							Host:    "primary.local",
+							Port:    5432,
+							Timeout: 30,
+						},
+					},
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := api.NewClient(&api.Config{
+				HttpClient: &http.Client{},
+			})
+			if err != nil {
+				t.Error("Failed to start the client!")
+			}
+			parser := &Parser{
+				layers: kvLayers(client.KV()),
+			}
+			target := tt.args()
+			if err := parser.Parse(target); err != nil {
+				t.Errorf("Parser.Parse() error = %v", err)
+			}
+			assert.EqualValues(t, tt.expectResult(), target)
+		})
+	}
+}
+
+func TestParser_ParseContext(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	const (
+		responseJSON = `[
+				{
+					"LockIndex": 0,
+					"Key": "%s",
+					"Flags": 0,
+					"Value": "%s",
+					"CreateIndex": 0,
+					"ModifyIndex": 0
+				}
+			]
+		`
+	)
+	stringResp := fmt.Sprintf(responseJSON, "string", base64.StdEncoding.EncodeToString([]byte("hello")))
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/string",
+		httpmock.NewStringResponder(http.StatusOK, stringResp),
+	)
+	//The hung key only answers when the request is cancelled.
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/hung",
+		func(req *http.Request) (*http.Response, error) {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		},
+	)
+	type target struct {
+		String string `consulkv:"string"`
+		Hung   string `consulkv:"hung"`
+	}
+	tests := []struct {
+		name         string
+		ctx          func() (context.Context, context.CancelFunc)
+		args         func() interface{}
+		wantErr      error
+		expectResult func() interface{}
+	}{
+		{
+			name: "Cancelled Context",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			args: func() interface{} {
+				return &target{
+					String: "untouched",
+				}
+			},
+			wantErr: context.Canceled,
+			expectResult: func() interface{} {
+				return &target{
+					String: "untouched",
+				}
+			},
+		},
+		{
+			name: "Deadline Exceeded on Hung Key",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			args: func() interface{} {
+				return &target{}
+			},
+			wantErr: context.DeadlineExceeded,
+			expectResult: func() interface{} {
+				return &target{}
+			},
+		},
+		{
+			name: "Live Context",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+			args: func() interface{} {
+				return &struct {
+					String string `consulkv:"string"`
+				}{}
+			},
+			wantErr: nil,
+			expectResult: func() interface{} {
+				return &struct {
+					String string `consulkv:"string"`
+				}{
+					String: "hello",
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := api.NewClient(&api.Config{
+				HttpClient: &http.Client{},
+			})
+			if err != nil {
+				t.Error("Failed to start the client!")
+			}
+			parser := &Parser{
+				layers: kvLayers(client.KV()),
+			}
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			target := tt.args()
+			if err := parser.ParseContext(ctx, target); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parser.ParseContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.EqualValues(t, tt.expectResult(), target)
+		})
+	}
+}
+
 func TestParser_SetTimeLayout(t *testing.T) {
 	type fields struct {
 		consulKV func() *api.KV
@@ -1221,11 +1868,8 @@ func TestParser_SetTimeLayout(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			defer func() {
-				timeLayout = time.RFC3339
-			}()
 			parser := &Parser{
-				consulKV: tt.fields.consulKV(),
+				layers: kvLayers(tt.fields.consulKV()),
 			}
 			if err := parser.SetTimeLayout(tt.args.layout()); (err != nil) != tt.wantErr {
 				t.Errorf("Parser.Parse() error = %v, wantErr %v", err, tt.wantErr)
@@ -1233,7 +1877,205 @@ func TestParser_SetTimeLayout(t *testing.T) {
 			if !tt.wantErr {
 				assert.Equal(t, tt.expects.layout(), tt.args.layout())
 			}
-			assert.Equal(t, tt.expects.layout(), timeLayout)
+			assert.Equal(t, tt.expects.layout(), parser.layout())
+		})
+	}
+}
+
+func TestParser_Parse_Prefetch(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	const (
+		pairJSON = `{
+				"LockIndex": 0,
+				"Key": "%s",
+				"Flags": 0,
+				"Value": "%s",
+				"CreateIndex": 0,
+				"ModifyIndex": 0
+			}`
+	)
+	appResp := "[" + fmt.Sprintf(pairJSON, "app/name", base64.StdEncoding.EncodeToString([]byte("hello"))) + "," +
+		fmt.Sprintf(pairJSON, "app/db/host", base64.StdEncoding.EncodeToString([]byte("localhost"))) + "," +
+		fmt.Sprintf(pairJSON, "app/db/port", base64.StdEncoding.EncodeToString([]byte("5432"))) + "]"
+	flagResp := "[" + fmt.Sprintf(pairJSON, "flag", base64.StdEncoding.EncodeToString([]byte("true"))) + "]"
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/app/?recurse=",
+		httpmock.NewStringResponder(http.StatusOK, appResp),
+	)
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/flag",
+		httpmock.NewStringResponder(http.StatusOK, flagResp),
+	)
+	type args struct {
+		target interface{}
+	}
+	type DBConfig struct {
+		Host string `consulkv:"app/db/host"`
+		Port *int64 `consulkv:"app/db/port"`
+	}
+	tests := []struct {
+		name         string
+		args         func() args
+		wantErr      bool
+		wantCalls    int
+		expectResult func() interface{}
+	}{
+		{
+			name: "All Keys in One Request",
+			args: func() args {
+				return args{
+					target: &struct {
+						Name     string `consulkv:"app/name"`
+						Database DBConfig
+					}{},
+				}
+			},
+			wantErr:   false,
+			wantCalls: 1,
+			expectResult: func() interface{} {
+				port := int64(5432)
+				return &struct {
+					Name     string `consulkv:"app/name"`
+					Database DBConfig
+				}{
+					Name: "hello",
+					Database: DBConfig{
+						Host: "localhost",
+						Port: &port,
+					},
+				}
+			},
+		},
+		{
+			name: "One Request per Key Group",
+			args: func() args {
+				return args{
+					target: &struct {
+						Name    string `consulkv:"app/name"`
+						Flag    bool   `consulkv:"flag"`
+						Missing string `consulkv:"app/missing"`
+					}{},
+				}
+			},
+			wantErr:   false,
+			wantCalls: 2,
+			expectResult: func() interface{} {
+				return &struct {
+					Name    string `consulkv:"app/name"`
+					Flag    bool   `consulkv:"flag"`
+					Missing string `consulkv:"app/missing"`
+				}{
+					Name: "hello",
+					Flag: true,
+				}
+			},
+		},
+		{
+			name: "Failed List Request",
+			args: func() args {
+				return args{
+					target: &struct {
+						Name string `consulkv:"other/name"`
+					}{},
+				}
+			},
+			wantErr:   true,
+			wantCalls: 0,
+			expectResult: func() interface{} {
+				return &struct {
+					Name string `consulkv:"other/name"`
+				}{}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpmock.ZeroCallCounters()
+			client, err := api.NewClient(&api.Config{
+				HttpClient: &http.Client{},
+			})
+			if err != nil {
+				t.Error("Failed to start the client!")
+			}
+			parser, err := NewParser(client, WithPrefetch(true), WithMissingKeyPolicy(MissingKeySkip))
+			assert.NoError(t, err)
+			target := tt.args().target
+			if err := parser.Parse(target); (err != nil) != tt.wantErr {
+				t.Errorf("Parser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.wantCalls, httpmock.GetTotalCallCount())
+			assert.EqualValues(t, tt.expectResult(), target)
+		})
+	}
+}
+
+func TestParser_Parse_Duration(t *testing.T) {
+	type target struct {
+		Timeout       time.Duration   `consulkv:"timeout"`
+		Interval      *time.Duration  `consulkv:"interval"`
+		RetryInterval **time.Duration `consulkv:"retry"`
+	}
+	tests := []struct {
+		name       string
+		values     map[string]string
+		wantResult func() *target
+		wantErr    bool
+	}{
+		{
+			name: "Duration Values",
+			values: map[string]string{
+				"timeout":  "30s",
+				"interval": "5m",
+				"retry":    "1h30m",
+			},
+			wantResult: func() *target {
+				interval := 5 * time.Minute
+				retry := 90 * time.Minute
+				retryPtr := &retry
+				return &target{
+					Timeout:       30 * time.Second,
+					Interval:      &interval,
+					RetryInterval: &retryPtr,
+				}
+			},
+		},
+		{
+			name: "Duration Without Unit",
+			values: map[string]string{
+				"timeout":  "30",
+				"interval": "5m",
+				"retry":    "1h",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Pointer Duration Without Unit",
+			values: map[string]string{
+				"timeout":  "30s",
+				"interval": "5",
+				"retry":    "1h",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParserWithSource(NewMemorySource(tt.values))
+			assert.NoError(t, err)
+			result := &target{}
+			err = parser.Parse(result)
+			if tt.wantErr {
+				var fieldErr *FieldError
+				assert.True(t, errors.As(err, &fieldErr))
+				assert.Equal(t, reflect.Int64, fieldErr.Kind)
+				assert.Equal(t, &target{}, result)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantResult(), result)
 		})
 	}
 }
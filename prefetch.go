@@ -0,0 +1,160 @@
+package consulparser
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+//parseState holds the data that only lives for a single Parse call.
+type parseState struct {
+	ctx context.Context
+	//snapshot tells parseTarget to read the pairs through Consul transactions.
+	snapshot bool
+	//index holds the highest ModifyIndex of the snapshot.
+	index uint64
+	//pairs holds the prefetched pairs of every layer indexed by their key.
+	//It is nil when the parser reads every key with its own request.
+	pairs []map[string]*api.KVPair
+	//report holds the provenance of every parsed field when it isn't nil.
+	report Report
+	//errs holds the field errors collected when the parser doesn't stop at the first one.
+	errs []error
+}
+
+//prefetchPairs reads all keys used by the target value with one KV.List per key group and layer,
+//and the keys without a folder with one KV.Get per key and layer.
+//Environment layers can't list, so every key is read from them with Get.
+func (parser *Parser) prefetchPairs(state *parseState, val reflect.Value) (pairs []map[string]*api.KVPair, err error) {
+	keys := parser.targetKeys(val)
+	prefixes, flatKeys := keyPrefixes(keys)
+	for _, layer := range parser.layers {
+		layerPrefixes, layerKeys := prefixes, flatKeys
+		if _, ok := layer.Source.(*envSource); ok {
+			layerPrefixes, layerKeys = nil, keys
+		}
+		layerPairs := make(map[string]*api.KVPair)
+		for _, prefix := range layerPrefixes {
+			var list api.KVPairs
+			list, err = layer.Source.List(state.ctx, prefix)
+			if err != nil {
+				return
+			}
+			for _, pair := range list {
+				layerPairs[pair.Key] = pair
+			}
+		}
+		for _, key := range layerKeys {
+			var pair *api.KVPair
+			pair, err = layer.Source.Get(state.ctx, key)
+			if err != nil {
+				return
+			}
+			if pair != nil {
+				layerPairs[pair.Key] = pair
+			}
+		}
+		pairs = append(pairs, layerPairs)
+	}
+	return
+}
+
+//targetKeys returns every tagged key used by the target value.
+func (parser *Parser) targetKeys(val reflect.Value) (keys []string) {
+	if !val.IsValid() {
+		return
+	}
+	parser.collectKeys(val.Type(), "", &keys, make(map[reflect.Type]bool))
+	return
+}
+
+//collectKeys walks the type the same way parse walks the value and gathers every tagged key.
+//walking holds the struct types being walked, so recursive types of folder elements are walked once.
+func (parser *Parser) collectKeys(typ reflect.Type, prefix string, keys *[]string, walking map[reflect.Type]bool) {
+	typ = indirectType(typ)
+	if !parser.isNestedStruct(typ) || walking[typ] {
+		return
+	}
+	walking[typ] = true
+	defer delete(walking, typ)
+	for index := 0; index < typ.NumField(); index++ {
+		field := typ.Field(index)
+		//Unexported fields can't be set by parse.
+		if field.PkgPath != "" {
+			continue
+		}
+		tagOpts := parseTag(field.Tag.Get(parser.tag()))
+		if tagOpts.format == "" && parser.isNestedStruct(indirectType(field.Type)) {
+			parser.collectKeys(field.Type, resolvePrefix(prefix, tagOpts.key), keys, walking)
+			continue
+		}
+		//Keys with a broken template are left out, parse reports them on their field.
+		consulKey, err := parser.expandKey(parser.resolveFieldKey(prefix, tagOpts, field.Type))
+		if err != nil || consulKey == "" {
+			continue
+		}
+		*keys = append(*keys, consulKey)
+		if tagOpts.format == "" && parser.isFolder(indirectType(field.Type)) {
+			parser.collectFolderKeys(indirectType(field.Type), resolvePrefix(prefix, tagOpts.key), consulKey, keys, walking)
+		}
+	}
+}
+
+//collectFolderKeys gathers the keys of the structs of a folder that aren't under the folder,
+//i.e. their absolute keys. The other keys are read with the folder, since the names of its subfolders
+//are only known at parse time.
+func (parser *Parser) collectFolderKeys(typ reflect.Type, prefix, folder string, keys *[]string, walking map[reflect.Type]bool) {
+	var elemKeys []string
+	parser.collectKeys(typ.Elem(), prefix, &elemKeys, walking)
+	for _, key := range elemKeys {
+		if !strings.HasPrefix(key, folder) {
+			*keys = append(*keys, key)
+		}
+	}
+}
+
+//keyPrefixes groups the keys in a folder by their first path segment and returns the longest common prefix
+//of each group, along with the keys without a folder, which are read one by one.
+//A prefix is never empty, so the whole store is never read.
+func keyPrefixes(keys []string) (prefixes, flatKeys []string) {
+	groups := make(map[string]string)
+	flat := make(map[string]bool)
+	for _, key := range keys {
+		index := strings.Index(key, "/")
+		if index < 0 {
+			flat[key] = true
+			continue
+		}
+		segment := key[:index+1]
+		prefix, ok := groups[segment]
+		if !ok {
+			groups[segment] = key
+			continue
+		}
+		groups[segment] = commonPrefix(prefix, key)
+	}
+	for _, prefix := range groups {
+		prefixes = append(prefixes, prefix)
+	}
+	for key := range flat {
+		flatKeys = append(flatKeys, key)
+	}
+	sort.Strings(prefixes)
+	sort.Strings(flatKeys)
+	return
+}
+
+func commonPrefix(first, second string) string {
+	length := len(first)
+	if len(second) < length {
+		length = len(second)
+	}
+	index := 0
+	for index < length && first[index] == second[index] {
+		index++
+	}
+	return first[:index]
+}
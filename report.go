@@ -0,0 +1,50 @@
+package consulparser
+
+import (
+	"context"
+)
+
+//FieldReport defines where the value of a parsed field comes from.
+type FieldReport struct {
+	//Key is the consul key of the field.
+	Key string
+	//Layer is the name of the layer holding the key, LayerEnv or LayerDefault.
+	//It is empty when the key is missing.
+	Layer string
+	//ModifyIndex is the ModifyIndex of the pair read from the layer, zero for the other values.
+	ModifyIndex uint64
+	//Default is set when the value comes from the default option of the tag.
+	Default bool
+	//Missing is set when the key is missing and the field is handled by the missing key policy.
+	Missing bool
+	//Empty is set when the value is empty, which leaves the field untouched.
+	Empty bool
+}
+
+//Report defines the provenance of the parsed fields indexed by their Go field path, e.g. Database.Primary.Port.
+//Fields without a key nor an environment variable aren't reported.
+type Report map[string]FieldReport
+
+//ParseReport is like ParseContext, but also returns the provenance of every field,
+//e.g. to explain the effective configuration on a debug page.
+//The report is nil when ParseReport returns an error.
+func (parser *Parser) ParseReport(ctx context.Context, target interface{}) (report Report, err error) {
+	state := &parseState{
+		ctx:    ctx,
+		report: make(Report),
+	}
+	err = parser.parseTarget(state, target)
+	if err != nil {
+		return
+	}
+	report = state.report
+	return
+}
+
+//record keeps the provenance of the field when the state collects a report.
+func (state *parseState) record(path string, fieldReport FieldReport) {
+	if state.report == nil || (fieldReport.Key == "" && fieldReport.Layer == "") {
+		return
+	}
+	state.report[path] = fieldReport
+}
@@ -0,0 +1,74 @@
+package consulparser
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParser_ParseReport(t *testing.T) {
+	type DBConfig struct {
+		Host     string `consulkv:"host"`
+		Port     int    `consulkv:"port,default=5432"`
+		User     string `consulkv:"user"`
+		Password string `env:"DB_PASSWORD"`
+	}
+	type target struct {
+		Name    string   `consulkv:"name"`
+		DB      DBConfig `consulkv:"db/"`
+		Timeout int      `consulkv:"timeout"`
+		Comment string
+	}
+	source := NewMemorySource(map[string]string{
+		"production/name":    "billing",
+		"production/db/user": "",
+	})
+	source.Set("production/db/host", "db.local")
+	t.Setenv("DB_PASSWORD", "secret")
+	parser, err := NewParserWithSource(source,
+		WithRootPrefix("production"),
+		WithEnvOverrides(""),
+		WithMissingKeyPolicy(MissingKeySkip),
+	)
+	assert.NoError(t, err)
+	result := &target{}
+	report, err := parser.ParseReport(context.Background(), result)
+	assert.NoError(t, err)
+	assert.Equal(t, Report{
+		"Name": {
+			Key:         "production/name",
+			Layer:       LayerSource,
+			ModifyIndex: 1,
+		},
+		"DB.Host": {
+			Key:         "production/db/host",
+			Layer:       LayerSource,
+			ModifyIndex: 2,
+		},
+		"DB.Port": {
+			Key:     "production/db/port",
+			Layer:   LayerDefault,
+			Default: true,
+		},
+		"DB.User": {
+			Key:         "production/db/user",
+			Layer:       LayerSource,
+			ModifyIndex: 1,
+			Empty:       true,
+		},
+		"DB.Password": {
+			Layer: LayerEnv,
+		},
+		"Timeout": {
+			Key:     "production/timeout",
+			Missing: true,
+		},
+	}, report)
+	//A failed parse has no report.
+	source.Set("production/timeout", "abc")
+	report, err = parser.ParseReport(context.Background(), result)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+	assert.Nil(t, report)
+}
@@ -0,0 +1,152 @@
+package consulparser
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	//maxTxnOps is the maximum number of operations that Consul accepts in a single transaction.
+	maxTxnOps = 64
+	//maxSnapshotAttempts is the number of times a chunked snapshot is read before giving up.
+	maxSnapshotAttempts = 3
+)
+
+//ParseSnapshot gives the value to the target like Parse, but reads every key of the target
+//inside Consul transactions so all fields come from the same Raft index.
+//The returned index is the highest ModifyIndex of the keys of the target.
+//Targets needing more than 64 operations are read with several transactions,
+//which are retried until the keys of the first ones are unchanged after reading the last one.
+//It fails with ErrUnsupportedSource when the parser doesn't read from consul.
+func (parser *Parser) ParseSnapshot(target interface{}) (index uint64, err error) {
+	index, err = parser.ParseSnapshotContext(context.Background(), target)
+	return
+}
+
+//ParseSnapshotContext is like ParseSnapshot, but every transaction is bound to the ctx.
+func (parser *Parser) ParseSnapshotContext(ctx context.Context, target interface{}) (index uint64, err error) {
+	state := &parseState{
+		ctx:      ctx,
+		snapshot: true,
+	}
+	err = parser.parseTarget(state, target)
+	if err != nil {
+		return
+	}
+	index = state.index
+	return
+}
+
+func (parser *Parser) snapshotPairs(state *parseState, val reflect.Value) (pairs map[string]*api.KVPair, index uint64, err error) {
+	//Only consul can read several prefixes at the same index.
+	source, ok := parser.kvSource().(*consulSource)
+	if !ok || source.txn == nil {
+		err = ErrUnsupportedSource
+		return
+	}
+	prefixes, flatKeys := keyPrefixes(parser.targetKeys(val))
+	//A KVGet of a missing key rolls back the whole transaction, so the keys without a folder
+	//are read with a get-tree on the exact key as well.
+	prefixes = append(prefixes, flatKeys...)
+	for attempt := 0; attempt < maxSnapshotAttempts; attempt++ {
+		var consistent bool
+		pairs, index, consistent, err = source.readSnapshot(state.ctx, prefixes)
+		if err != nil || consistent {
+			return
+		}
+	}
+	pairs, index, err = nil, 0, ErrInconsistentSnapshot
+	return
+}
+
+//readSnapshot reads the prefixes in chunks of maxTxnOps get-tree operations.
+//The read-only transactions don't report the Raft index, so the index is the highest ModifyIndex of the pairs.
+//Every chunk but the last is read again after the last one, and consistent is false when one of them changed,
+//since their pairs may then come from another index than the pairs of the next chunks.
+func (source *consulSource) readSnapshot(ctx context.Context, prefixes []string) (pairs map[string]*api.KVPair, index uint64, consistent bool, err error) {
+	var chunks [][]string
+	for start := 0; start < len(prefixes); start += maxTxnOps {
+		end := start + maxTxnOps
+		if end > len(prefixes) {
+			end = len(prefixes)
+		}
+		chunks = append(chunks, prefixes[start:end])
+	}
+	chunkPairs := make([]map[string]*api.KVPair, len(chunks))
+	for chunkIndex, chunk := range chunks {
+		chunkPairs[chunkIndex], err = source.readChunk(ctx, chunk)
+		if err != nil {
+			return
+		}
+	}
+	for chunkIndex := 0; chunkIndex < len(chunks)-1; chunkIndex++ {
+		var reread map[string]*api.KVPair
+		reread, err = source.readChunk(ctx, chunks[chunkIndex])
+		if err != nil || !sameModifyIndexes(chunkPairs[chunkIndex], reread) {
+			return
+		}
+	}
+	consistent = true
+	pairs = make(map[string]*api.KVPair)
+	for _, chunk := range chunkPairs {
+		for key, pair := range chunk {
+			pairs[key] = pair
+			if pair.ModifyIndex > index {
+				index = pair.ModifyIndex
+			}
+		}
+	}
+	return
+}
+
+//readChunk reads the prefixes with a single transaction of get-tree operations.
+func (source *consulSource) readChunk(ctx context.Context, prefixes []string) (pairs map[string]*api.KVPair, err error) {
+	ops := make(api.TxnOps, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		ops = append(ops, &api.TxnOp{
+			KV: &api.KVTxnOp{
+				Verb: api.KVGetTree,
+				Key:  prefix,
+			},
+		})
+	}
+	ok, resp, _, err := source.txn.Txn(ops, source.requestOptions(ctx))
+	if err != nil {
+		return
+	}
+	if !ok {
+		err = txnError(resp)
+		return
+	}
+	pairs = make(map[string]*api.KVPair, len(resp.Results))
+	for _, result := range resp.Results {
+		if result.KV != nil {
+			pairs[result.KV.Key] = result.KV
+		}
+	}
+	return
+}
+
+//sameModifyIndexes reports whether both reads hold the same keys with the same ModifyIndex.
+func sameModifyIndexes(first, second map[string]*api.KVPair) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for key, pair := range first {
+		other, ok := second[key]
+		if !ok || other.ModifyIndex != pair.ModifyIndex {
+			return false
+		}
+	}
+	return true
+}
+
+func txnError(resp *api.TxnResponse) error {
+	if resp == nil || len(resp.Errors) == 0 {
+		return ErrTxnRollback
+	}
+	return fmt.Errorf("%w: %s", ErrTxnRollback, resp.Errors[0].What)
+}
@@ -0,0 +1,218 @@
+package consulparser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+//txnResponder answers every get-tree operation with the values whose key starts with the operation key,
+//using index as the ModifyIndex of the pairs of every transaction.
+func txnResponder(values map[string]string, index func() uint64) httpmock.Responder {
+	return func(req *http.Request) (resp *http.Response, err error) {
+		var ops api.TxnOps
+		err = json.NewDecoder(req.Body).Decode(&ops)
+		if err != nil {
+			return
+		}
+		txnResp := api.TxnResponse{}
+		modifyIndex := index()
+		for _, op := range ops {
+			for key, value := range values {
+				if !strings.HasPrefix(key, op.KV.Key) {
+					continue
+				}
+				txnResp.Results = append(txnResp.Results, &api.TxnResult{
+					KV: &api.KVPair{
+						Key:         key,
+						Value:       []byte(value),
+						ModifyIndex: modifyIndex,
+					},
+				})
+			}
+		}
+		resp, err = httpmock.NewJsonResponse(http.StatusOK, txnResp)
+		return
+	}
+}
+
+func TestParser_ParseSnapshot(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	values := map[string]string{
+		"db/host":     "localhost",
+		"db/password": "secret",
+		"port":        "5432",
+	}
+	//manyFields builds a struct type that needs more than one transaction.
+	manyFields := func() reflect.Type {
+		var fields []reflect.StructField
+		for index := 0; index <= maxTxnOps; index++ {
+			key := fmt.Sprintf("key%d", index)
+			values[key] = strconv.Itoa(index)
+			fields = append(fields, reflect.StructField{
+				Name: fmt.Sprintf("Field%d", index),
+				Type: reflect.TypeOf(0),
+				Tag:  reflect.StructTag(fmt.Sprintf(`consulkv:"%s"`, key)),
+			})
+		}
+		return reflect.StructOf(fields)
+	}()
+	type args struct {
+		target interface{}
+	}
+	tests := []struct {
+		name         string
+		responder    func() httpmock.Responder
+		args         func() args
+		wantIndex    uint64
+		wantErr      error
+		expectResult func() interface{}
+	}{
+		{
+			name: "Single Transaction",
+			responder: func() httpmock.Responder {
+				return txnResponder(values, func() uint64 {
+					return 42
+				})
+			},
+			args: func() args {
+				return args{
+					target: &struct {
+						Host     string `consulkv:"db/host"`
+						Password string `consulkv:"db/password"`
+						Port     int    `consulkv:"port"`
+					}{},
+				}
+			},
+			wantIndex: 42,
+			expectResult: func() interface{} {
+				return &struct {
+					Host     string `consulkv:"db/host"`
+					Password string `consulkv:"db/password"`
+					Port     int    `consulkv:"port"`
+				}{
+					Host:     "localhost",
+					Password: "secret",
+					Port:     5432,
+				}
+			},
+		},
+		{
+			name: "Chunked Transactions on the Same Index",
+			responder: func() httpmock.Responder {
+				return txnResponder(values, func() uint64 {
+					return 7
+				})
+			},
+			args: func() args {
+				return args{
+					target: reflect.New(manyFields).Interface(),
+				}
+			},
+			wantIndex: 7,
+			expectResult: func() interface{} {
+				result := reflect.New(manyFields)
+				for index := 0; index < manyFields.NumField(); index++ {
+					result.Elem().Field(index).SetInt(int64(index))
+				}
+				return result.Interface()
+			},
+		},
+		{
+			name: "Chunked Transactions Never Agree",
+			responder: func() httpmock.Responder {
+				var index uint64
+				return txnResponder(values, func() uint64 {
+					index++
+					return index
+				})
+			},
+			args: func() args {
+				return args{
+					target: reflect.New(manyFields).Interface(),
+				}
+			},
+			wantErr: ErrInconsistentSnapshot,
+			expectResult: func() interface{} {
+				return reflect.New(manyFields).Interface()
+			},
+		},
+		{
+			name: "Rolled Back Transaction",
+			responder: func() httpmock.Responder {
+				return httpmock.NewJsonResponderOrPanic(http.StatusConflict, api.TxnResponse{
+					Errors: api.TxnErrors{
+						{
+							OpIndex: 0,
+							What:    "permission denied",
+						},
+					},
+				})
+			},
+			args: func() args {
+				return args{
+					target: &struct {
+						Port int `consulkv:"port"`
+					}{},
+				}
+			},
+			wantErr: ErrTxnRollback,
+			expectResult: func() interface{} {
+				return &struct {
+					Port int `consulkv:"port"`
+				}{}
+			},
+		},
+		{
+			name: "Non Pointer Type",
+			responder: func() httpmock.Responder {
+				return txnResponder(values, func() uint64 {
+					return 1
+				})
+			},
+			args: func() args {
+				return args{
+					target: struct {
+						Port int `consulkv:"port"`
+					}{},
+				}
+			},
+			wantErr: ErrNonPointerType,
+			expectResult: func() interface{} {
+				return struct {
+					Port int `consulkv:"port"`
+				}{}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpmock.RegisterResponder(http.MethodPut, "http://127.0.0.1:8500/v1/txn", tt.responder())
+			client, err := api.NewClient(&api.Config{
+				HttpClient: &http.Client{},
+			})
+			if err != nil {
+				t.Error("Failed to start the client!")
+			}
+			parser, err := NewParser(client)
+			assert.NoError(t, err)
+			target := tt.args().target
+			index, err := parser.ParseSnapshot(target)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parser.ParseSnapshot() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.wantIndex, index)
+			assert.EqualValues(t, tt.expectResult(), target)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package consulparser
+
+import (
+	"context"
+
+	"github.com/hashicorp/consul/api"
+)
+
+//Source defines the key/value store that the parser reads the tagged keys from.
+//Implementations must be safe for concurrent use, since a Parser may be used from multiple goroutines.
+type Source interface {
+	//Get returns the pair of the key, or nil without an error when the key doesn't exist.
+	Get(ctx context.Context, key string) (*api.KVPair, error)
+	//List returns every pair whose key starts with the prefix.
+	List(ctx context.Context, prefix string) (api.KVPairs, error)
+}
+
+//WatchSource defines a Source that can block until the pairs under a prefix change.
+//Watch only works with sources that implement it.
+type WatchSource interface {
+	Source
+	//WatchList is like List, but blocks until the index of the store is past waitIndex or the ctx is done.
+	//A zero waitIndex returns at once. The returned index is passed as waitIndex of the next call.
+	WatchList(ctx context.Context, prefix string, waitIndex uint64) (api.KVPairs, uint64, error)
+}
+
+//consulSource defines the Source reading from the consul KV API.
+//It is the default source of the parsers built with NewParser.
+type consulSource struct {
+	kv           *api.KV
+	txn          *api.Txn
+	queryOptions *api.QueryOptions
+}
+
+//NewConsulSource returns the Source reading from the KV API of the consul client with the query options.
+//Parsers built with NewParserWithSource don't pass their own query options to the source.
+func NewConsulSource(client *api.Client, queryOptions *api.QueryOptions) (source Source, err error) {
+	if client == nil {
+		err = ErrNilClient
+		return
+	}
+	newSource := &consulSource{
+		kv:  client.KV(),
+		txn: client.Txn(),
+	}
+	if queryOptions != nil {
+		copied := *queryOptions
+		newSource.queryOptions = &copied
+	}
+	source = newSource
+	return
+}
+
+func (source *consulSource) Get(ctx context.Context, key string) (pair *api.KVPair, err error) {
+	pair, _, err = source.kv.Get(key, source.requestOptions(ctx))
+	return
+}
+
+func (source *consulSource) List(ctx context.Context, prefix string) (pairs api.KVPairs, err error) {
+	pairs, _, err = source.kv.List(prefix, source.requestOptions(ctx))
+	return
+}
+
+//WatchList runs a consul blocking query on the prefix.
+func (source *consulSource) WatchList(ctx context.Context, prefix string, waitIndex uint64) (pairs api.KVPairs, index uint64, err error) {
+	queryOptions := source.requestOptions(ctx)
+	queryOptions.WaitIndex = waitIndex
+	var meta *api.QueryMeta
+	pairs, meta, err = source.kv.List(prefix, queryOptions)
+	if err != nil {
+		return
+	}
+	index = meta.LastIndex
+	return
+}
+
+//requestOptions returns a copy of the query options of the source bound to the ctx.
+func (source *consulSource) requestOptions(ctx context.Context) *api.QueryOptions {
+	queryOptions := &api.QueryOptions{}
+	if source.queryOptions != nil {
+		*queryOptions = *source.queryOptions
+	}
+	return queryOptions.WithContext(ctx)
+}
+
+//kvSource returns the source of a parser with a single layer, or nil for parsers with several layers.
+func (parser *Parser) kvSource() Source {
+	if len(parser.layers) != 1 {
+		return nil
+	}
+	return parser.layers[0].Source
+}
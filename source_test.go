@@ -0,0 +1,200 @@
+package consulparser
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+//mapSource is a Source reading from a map that counts its requests.
+type mapSource struct {
+	values map[string]string
+	gets   int
+	lists  int
+}
+
+func (source *mapSource) Get(ctx context.Context, key string) (*api.KVPair, error) {
+	source.gets++
+	value, ok := source.values[key]
+	if !ok {
+		return nil, nil
+	}
+	return &api.KVPair{Key: key, Value: []byte(value)}, nil
+}
+
+func (source *mapSource) List(ctx context.Context, prefix string) (pairs api.KVPairs, err error) {
+	source.lists++
+	for key, value := range source.values {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, &api.KVPair{Key: key, Value: []byte(value)})
+		}
+	}
+	return
+}
+
+func TestNewParserWithSource(t *testing.T) {
+	type DBConfig struct {
+		Host string `kv:"host"`
+		Port int    `kv:"port"`
+	}
+	type target struct {
+		Name string   `kv:"name"`
+		DB   DBConfig `kv:"db/"`
+	}
+	values := map[string]string{
+		"app/name":    "billing",
+		"app/db/host": "localhost",
+		"app/db/port": "5432",
+	}
+	tests := []struct {
+		name      string
+		source    Source
+		opts      []Option
+		wantErr   error
+		wantGets  int
+		wantLists int
+	}{
+		{
+			name:     "Get Every Key",
+			source:   &mapSource{values: values},
+			opts:     []Option{WithTagName("kv"), WithRootPrefix("app")},
+			wantGets: 3,
+		},
+		{
+			name:      "Prefetch Every Key",
+			source:    &mapSource{values: values},
+			opts:      []Option{WithTagName("kv"), WithRootPrefix("app"), WithPrefetch(true)},
+			wantLists: 1,
+		},
+		{
+			name:    "Nil Source",
+			wantErr: ErrNilSource,
+		},
+		{
+			name:    "Failed Option",
+			source:  &mapSource{values: values},
+			opts:    []Option{WithTagName("")},
+			wantErr: ErrEmptyTagName,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParserWithSource(tt.source, tt.opts...)
+			if err != tt.wantErr {
+				t.Errorf("NewParserWithSource() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr != nil {
+				assert.Nil(t, parser)
+				return
+			}
+			result := &target{}
+			assert.NoError(t, parser.Parse(result))
+			assert.Equal(t, &target{
+				Name: "billing",
+				DB: DBConfig{
+					Host: "localhost",
+					Port: 5432,
+				},
+			}, result)
+			source := tt.source.(*mapSource)
+			assert.Equal(t, tt.wantGets, source.gets)
+			assert.Equal(t, tt.wantLists, source.lists)
+		})
+	}
+}
+
+func TestParser_Parse_PrefetchFlatKeys(t *testing.T) {
+	type target struct {
+		String  string  `consulkv:"string"`
+		Integer int     `consulkv:"integer"`
+		Float   float64 `consulkv:"float"`
+		Host    string  `consulkv:"db/host"`
+	}
+	source := &mapSource{values: map[string]string{
+		"string":  "hello",
+		"integer": "1",
+		"float":   "1.5",
+		"db/host": "localhost",
+	}}
+	parser, err := NewParserWithSource(source, WithPrefetch(true))
+	assert.NoError(t, err)
+	result := &target{}
+	assert.NoError(t, parser.Parse(result))
+	assert.Equal(t, &target{
+		String:  "hello",
+		Integer: 1,
+		Float:   1.5,
+		Host:    "localhost",
+	}, result)
+	//The db/ folder is read with a single list and the keys without a folder are read one by one,
+	//so the whole store is never listed.
+	assert.Equal(t, 1, source.lists)
+	assert.Equal(t, 3, source.gets)
+}
+
+func TestParser_UnsupportedSource(t *testing.T) {
+	type target struct {
+		Name string `consulkv:"name"`
+	}
+	parser, err := NewParserWithSource(&mapSource{})
+	assert.NoError(t, err)
+	_, err = parser.ParseSnapshot(&target{})
+	assert.Equal(t, ErrUnsupportedSource, err)
+	err = parser.Watch(context.Background(), &target{}, func(WatchEvent) {
+		t.Error("onChange must not be called")
+	})
+	assert.Equal(t, ErrUnsupportedSource, err)
+}
+
+func TestNewConsulSource(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	const (
+		responseJSON = `[
+				{
+					"LockIndex": 0,
+					"Key": "%s",
+					"Flags": 0,
+					"Value": "%s",
+					"CreateIndex": 0,
+					"ModifyIndex": 0
+				}
+			]
+		`
+	)
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"http://127.0.0.1:8500/v1/kv/name?dc=dc2",
+		httpmock.NewStringResponder(http.StatusOK, fmt.Sprintf(responseJSON, "name", base64.StdEncoding.EncodeToString([]byte("billing")))),
+	)
+	_, err := NewConsulSource(nil, nil)
+	assert.Equal(t, ErrNilClient, err)
+	client, err := api.NewClient(&api.Config{
+		HttpClient: &http.Client{},
+	})
+	if err != nil {
+		t.Error("Failed to start the client!")
+	}
+	queryOptions := &api.QueryOptions{
+		Datacenter: "dc2",
+	}
+	source, err := NewConsulSource(client, queryOptions)
+	assert.NoError(t, err)
+	//The options are copied by the source.
+	queryOptions.Datacenter = "dc3"
+	parser, err := NewParserWithSource(source)
+	assert.NoError(t, err)
+	result := &struct {
+		Name string `consulkv:"name"`
+	}{}
+	assert.NoError(t, parser.Parse(result))
+	assert.Equal(t, "billing", result.Name)
+}
@@ -0,0 +1,52 @@
+package consulparser
+
+import "strings"
+
+const (
+	optionRequired  = "required"
+	optionDefault   = "default="
+	optionSeparator = "sep="
+)
+
+//tagOptions defines the content of the struct tag, e.g. `consulkv:"db/port,required"`.
+type tagOptions struct {
+	key string
+	//required fails the parse when the key doesn't exist, regardless of the missing key policy.
+	required bool
+	//defaultValue is assigned with the usual conversion when the key doesn't exist.
+	defaultValue string
+	hasDefault   bool
+	//separator splits the value of a slice or an array field into its elements.
+	separator string
+	//format decodes the whole value into the field with the decoder of the format, e.g. `consulkv:"routing,json"`.
+	format string
+}
+
+//parseTag splits the struct tag into the key and its options.
+//The default option takes the rest of the tag, so its literal may contain commas
+//as long as it is the last option. An empty separator option followed by a comma,
+//e.g. `consulkv:"kafka/brokers,sep=,"`, sets the comma as the separator.
+//The name of a decoder, e.g. json, sets the format of the value. Unknown options are ignored.
+func parseTag(tag string) (options tagOptions) {
+	parts := strings.Split(tag, ",")
+	options.key = parts[0]
+	for index := 1; index < len(parts); index++ {
+		part := parts[index]
+		switch {
+		case part == optionRequired:
+			options.required = true
+		case decoders[part] != nil:
+			options.format = part
+		case part == optionSeparator && index+1 < len(parts) && parts[index+1] == "":
+			options.separator = ","
+			index++
+		case strings.HasPrefix(part, optionSeparator):
+			options.separator = strings.TrimPrefix(part, optionSeparator)
+		case strings.HasPrefix(part, optionDefault):
+			options.defaultValue = strings.TrimPrefix(strings.Join(parts[index:], ","), optionDefault)
+			options.hasDefault = true
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,110 @@
+package consulparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		want tagOptions
+	}{
+		{
+			name: "Key Only",
+			tag:  "db/host",
+			want: tagOptions{
+				key: "db/host",
+			},
+		},
+		{
+			name: "Required Key",
+			tag:  "db/host,required",
+			want: tagOptions{
+				key:      "db/host",
+				required: true,
+			},
+		},
+		{
+			name: "Default Value",
+			tag:  "db/port,default=5432",
+			want: tagOptions{
+				key:          "db/port",
+				defaultValue: "5432",
+				hasDefault:   true,
+			},
+		},
+		{
+			name: "Empty Default Value",
+			tag:  "db/user,default=",
+			want: tagOptions{
+				key:        "db/user",
+				hasDefault: true,
+			},
+		},
+		{
+			name: "Default Value with Commas",
+			tag:  "db/hosts,required,default=a,b,c",
+			want: tagOptions{
+				key:          "db/hosts",
+				required:     true,
+				defaultValue: "a,b,c",
+				hasDefault:   true,
+			},
+		},
+		{
+			name: "Separator",
+			tag:  "kafka/brokers,sep=;,required",
+			want: tagOptions{
+				key:       "kafka/brokers",
+				required:  true,
+				separator: ";",
+			},
+		},
+		{
+			name: "Comma Separator",
+			tag:  "kafka/brokers,sep=,,required",
+			want: tagOptions{
+				key:       "kafka/brokers",
+				required:  true,
+				separator: ",",
+			},
+		},
+		{
+			name: "Trailing Comma Separator",
+			tag:  "kafka/brokers,sep=,",
+			want: tagOptions{
+				key:       "kafka/brokers",
+				separator: ",",
+			},
+		},
+		{
+			name: "Format",
+			tag:  "routing,yaml,required",
+			want: tagOptions{
+				key:      "routing",
+				required: true,
+				format:   "yaml",
+			},
+		},
+		{
+			name: "Unknown Option",
+			tag:  "db/host,unknown",
+			want: tagOptions{
+				key: "db/host",
+			},
+		},
+		{
+			name: "Empty Tag",
+			tag:  "",
+			want: tagOptions{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseTag(tt.tag))
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package consulparser
+
+import (
+	"encoding"
+	"reflect"
+)
+
+//ConsulKVUnmarshaler defines a type that decodes itself from the raw value of its consul key.
+//It takes precedence over encoding.TextUnmarshaler when a type implements both.
+type ConsulKVUnmarshaler interface {
+	UnmarshalConsulKV(value string) error
+}
+
+var (
+	consulKVUnmarshalerType = reflect.TypeOf((*ConsulKVUnmarshaler)(nil)).Elem()
+	textUnmarshalerType     = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+//isUnmarshaler reports whether the pointer to the type implements one of the unmarshaler interfaces.
+//time.Time is left out, so it keeps being parsed with the layout of the parser.
+func isUnmarshaler(typ reflect.Type) bool {
+	if typ.String() == timeType {
+		return false
+	}
+	ptrType := reflect.PtrTo(typ)
+	return ptrType.Implements(consulKVUnmarshalerType) || ptrType.Implements(textUnmarshalerType)
+}
+
+//unmarshal decodes the value with the unmarshaler of the type of val or the type it points to.
+//handled is false when the type isn't an unmarshaler, so the value goes through the kind switch.
+func (parser *Parser) unmarshal(val reflect.Value, value string) (handled bool, err error) {
+	typ := val.Type()
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if !isUnmarshaler(typ) {
+		return
+	}
+	handled = true
+	if value == "" {
+		return
+	}
+	newVal := reflect.New(typ)
+	switch unmarshaler := newVal.Interface().(type) {
+	case ConsulKVUnmarshaler:
+		err = unmarshaler.UnmarshalConsulKV(value)
+	case encoding.TextUnmarshaler:
+		err = unmarshaler.UnmarshalText([]byte(value))
+	}
+	if err != nil {
+		return
+	}
+	if val.Kind() == reflect.Ptr {
+		val.Set(newVal)
+		return
+	}
+	val.Set(newVal.Elem())
+	return
+}
@@ -0,0 +1,124 @@
+package consulparser
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errUnknownLevel = errors.New("unknown log level")
+
+type logLevel int
+
+func (level *logLevel) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "debug":
+		*level = 0
+	case "info":
+		*level = 1
+	case "error":
+		*level = 2
+	default:
+		return errUnknownLevel
+	}
+	return nil
+}
+
+type version struct {
+	Major int
+	Minor int
+}
+
+func (ver *version) UnmarshalConsulKV(value string) error {
+	_, err := fmt.Sscanf(value, "v%d.%d", &ver.Major, &ver.Minor)
+	return err
+}
+
+//taggedName implements both unmarshalers, UnmarshalConsulKV must win.
+type taggedName string
+
+func (name *taggedName) UnmarshalConsulKV(value string) error {
+	*name = taggedName("consulkv:" + value)
+	return nil
+}
+
+func (name *taggedName) UnmarshalText(text []byte) error {
+	*name = taggedName("text:" + string(text))
+	return nil
+}
+
+func TestParser_Parse_Unmarshaler(t *testing.T) {
+	type target struct {
+		Level   logLevel   `consulkv:"level"`
+		Version *version   `consulkv:"version"`
+		Name    taggedName `consulkv:"name"`
+		IP      net.IP     `consulkv:"ip"`
+		Gateway *net.IP    `consulkv:"gateway"`
+		Mask    **logLevel `consulkv:"mask"`
+	}
+	values := map[string]string{
+		"level":   "error",
+		"version": "v1.2",
+		"name":    "billing",
+		"ip":      "10.0.0.1",
+		"gateway": "10.0.0.254",
+		"mask":    "info",
+	}
+	source := NewMemorySource(values)
+	parser, err := NewParserWithSource(source)
+	assert.NoError(t, err)
+	previous := &version{Major: 0, Minor: 9}
+	result := &target{
+		Version: previous,
+	}
+	assert.NoError(t, parser.Parse(result))
+	gateway := net.ParseIP("10.0.0.254")
+	mask := logLevel(1)
+	maskPtr := &mask
+	assert.Equal(t, &target{
+		Level:   2,
+		Version: &version{Major: 1, Minor: 2},
+		Name:    "consulkv:billing",
+		IP:      net.ParseIP("10.0.0.1"),
+		Gateway: &gateway,
+		Mask:    &maskPtr,
+	}, result)
+	//The pointers of a previous parse are replaced instead of mutated.
+	assert.Equal(t, &version{Major: 0, Minor: 9}, previous)
+	//Unmarshaler structs are assigned from their own key instead of being walked.
+	assert.Equal(t, []string{"level", "version", "name", "ip", "gateway", "mask"}, parser.(*Parser).targetKeys(reflect.ValueOf(result).Elem()))
+
+	//Empty values leave the field untouched and errors are reported on the field.
+	source.Set("level", "")
+	source.Set("version", "1.2")
+	err = parser.Parse(result)
+	var fieldErr *FieldError
+	assert.True(t, errors.As(err, &fieldErr))
+	assert.Equal(t, "Version", fieldErr.Path)
+	source.Set("version", "v2.0")
+	source.Set("mask", "trace")
+	err = parser.Parse(result)
+	assert.ErrorIs(t, err, errUnknownLevel)
+	source.Set("mask", "info")
+	assert.NoError(t, parser.Parse(result))
+	assert.Equal(t, logLevel(2), result.Level)
+	assert.Equal(t, &version{Major: 2, Minor: 0}, result.Version)
+}
+
+func TestParser_Parse_NilTarget(t *testing.T) {
+	type target struct {
+		Level logLevel `consulkv:"level"`
+	}
+	parser, err := NewParserWithSource(NewMemorySource(map[string]string{
+		"level": "info",
+	}))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, parser.Parse((*target)(nil)), ErrNonPointerType)
+	var nilTarget *target
+	assert.ErrorIs(t, parser.Parse(&nilTarget), ErrNonPointerType)
+}
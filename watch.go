@@ -0,0 +1,226 @@
+package consulparser
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	//watchMinRetryWait is the first wait before retrying a failed blocking query.
+	watchMinRetryWait = time.Second
+	//watchMaxRetryWait is the longest wait before retrying a failed blocking query.
+	watchMaxRetryWait = 30 * time.Second
+)
+
+//WatchEvent defines the reload delivered to the callback of Watch.
+type WatchEvent struct {
+	//Target is the target given to Watch, holding the newly parsed value.
+	Target interface{}
+	//Index is the consul index of the blocking query that triggered the reload.
+	Index uint64
+	//Changes lists the fields that differ from the previous value of the target.
+	//It is empty for the first parse and when Err is set.
+	Changes []Change
+	//Err is set when the reload or the blocking query fails.
+	//Target keeps its previous value in that case.
+	Err error
+}
+
+//watchResult defines the outcome of a blocking query on one of the key groups of the target.
+type watchResult struct {
+	group int
+	pairs api.KVPairs
+	index uint64
+	err   error
+}
+
+//Watch parses the target and keeps it up to date with consul blocking queries until the ctx is done.
+//Every group of keys sharing a folder, and every key without a folder, is watched with its own blocking list,
+//and the target is only parsed again when the ModifyIndex of one of its keys changes.
+//onChange is called from the goroutine running Watch after every parse, including the first one,
+//and after failed blocking queries, which are retried with a growing wait.
+//The target is updated in place, so readers on other goroutines must synchronize with onChange.
+//Watch returns the error of the ctx once it is done,
+//or ErrUnsupportedSource when the source of the parser doesn't implement WatchSource.
+func (parser *Parser) Watch(ctx context.Context, target interface{}, onChange func(WatchEvent)) (err error) {
+	valueStruct := reflect.ValueOf(target)
+	if valueStruct.Kind() != reflect.Ptr || !valueStruct.IsValid() {
+		return ErrNonPointerType
+	}
+	source, ok := parser.kvSource().(WatchSource)
+	if !ok {
+		return ErrUnsupportedSource
+	}
+	elemVal := parser.getRecursivePointerVal(valueStruct)
+	keys := parser.targetKeys(elemVal)
+	if len(keys) == 0 {
+		onChange(WatchEvent{
+			Target: target,
+			Err:    parser.ParseContext(ctx, target),
+		})
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	watched := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		watched[key] = true
+	}
+	prefixes, flatKeys := keyPrefixes(keys)
+	prefixes = append(prefixes, flatKeys...)
+	var waitGroup sync.WaitGroup
+	defer waitGroup.Wait()
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan watchResult)
+	for group, prefix := range prefixes {
+		waitGroup.Add(1)
+		go func(group int, prefix string) {
+			defer waitGroup.Done()
+			watchGroup(watchCtx, source, group, prefix, results)
+		}(group, prefix)
+	}
+	var (
+		lists      = make([]api.KVPairs, len(prefixes))
+		listed     = make([]bool, len(prefixes))
+		pending    = len(prefixes)
+		lastModify map[string]uint64
+		parsed     bool
+	)
+	for {
+		var result watchResult
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case result = <-results:
+		}
+		if result.err != nil {
+			onChange(WatchEvent{
+				Target: target,
+				Index:  result.index,
+				Err:    result.err,
+			})
+			continue
+		}
+		if !listed[result.group] {
+			listed[result.group] = true
+			pending--
+		}
+		lists[result.group] = result.pairs
+		//The first parse waits for every group to be listed.
+		if pending > 0 {
+			continue
+		}
+		var list api.KVPairs
+		for _, groupList := range lists {
+			list = append(list, groupList...)
+		}
+		pairs, modify := watchedPairs(list, watched)
+		if parsed && reflect.DeepEqual(modify, lastModify) {
+			continue
+		}
+		//The previous value only holds pointers that the parse replaces instead of mutating.
+		previous := reflect.New(elemVal.Type()).Elem()
+		previous.Set(elemVal)
+		state := &parseState{
+			ctx:   ctx,
+			pairs: []map[string]*api.KVPair{pairs},
+		}
+		parseErr := parser.parseTarget(state, target)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var changes []Change
+		if parsed && parseErr == nil {
+			changes, _ = parser.Diff(previous.Interface(), elemVal.Interface())
+		}
+		parsed, lastModify = true, modify
+		onChange(WatchEvent{
+			Target:  target,
+			Index:   result.index,
+			Changes: changes,
+			Err:     parseErr,
+		})
+	}
+}
+
+//watchGroup runs the blocking queries on the prefix and sends their results until the ctx is done.
+func watchGroup(ctx context.Context, source WatchSource, group int, prefix string, results chan<- watchResult) {
+	var lastIndex uint64
+	retryWait := watchMinRetryWait
+	for {
+		list, index, err := source.WatchList(ctx, prefix, lastIndex)
+		if ctx.Err() != nil {
+			return
+		}
+		result := watchResult{
+			group: group,
+			pairs: list,
+			index: index,
+		}
+		if err != nil {
+			result = watchResult{
+				group: group,
+				index: lastIndex,
+				err:   err,
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case results <- result:
+		}
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryWait):
+			}
+			retryWait *= 2
+			if retryWait > watchMaxRetryWait {
+				retryWait = watchMaxRetryWait
+			}
+			continue
+		}
+		retryWait = watchMinRetryWait
+		//The index may go backwards, e.g. after a snapshot restore, so the watch starts over.
+		if index < lastIndex {
+			index = 0
+		}
+		lastIndex = index
+	}
+}
+
+//watchedPairs keeps the listed pairs of the watched keys along with their ModifyIndex.
+//The watched keys ending with a slash are the folders of map, slice and array fields,
+//which watch their children and the keys of their subfolders.
+func watchedPairs(list api.KVPairs, watched map[string]bool) (pairs map[string]*api.KVPair, modify map[string]uint64) {
+	pairs = make(map[string]*api.KVPair)
+	modify = make(map[string]uint64)
+	for _, pair := range list {
+		if !isWatched(pair.Key, watched) {
+			continue
+		}
+		pairs[pair.Key] = pair
+		modify[pair.Key] = pair.ModifyIndex
+	}
+	return
+}
+
+//isWatched reports whether the key or one of its folders is watched.
+func isWatched(key string, watched map[string]bool) bool {
+	if watched[key] {
+		return true
+	}
+	for index := strings.LastIndex(key, "/"); index >= 0; index = strings.LastIndex(key, "/") {
+		key = key[:index]
+		if watched[key+"/"] {
+			return true
+		}
+	}
+	return false
+}
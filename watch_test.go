@@ -0,0 +1,194 @@
+package consulparser
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+//blockingResponder answers the blocking queries on the index with the listed pairs and the next index.
+//Indexes without a response block until the request is cancelled.
+func blockingResponder(responses map[string]api.KVPairs, nextIndex map[string]uint64, onBlock func()) httpmock.Responder {
+	return func(req *http.Request) (resp *http.Response, err error) {
+		index := req.URL.Query().Get("index")
+		pairs, ok := responses[index]
+		if !ok {
+			onBlock()
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		resp, err = httpmock.NewJsonResponse(http.StatusOK, pairs)
+		if err != nil {
+			return
+		}
+		resp.Header.Set("X-Consul-Index", strconv.FormatUint(nextIndex[index], 10))
+		return
+	}
+}
+
+func TestParser_Watch(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	type target struct {
+		Host string `consulkv:"app/db/host"`
+		Port int    `consulkv:"app/db/port"`
+	}
+	responses := map[string]api.KVPairs{
+		"": {
+			{Key: "app/db/host", Value: []byte("localhost"), ModifyIndex: 5},
+			{Key: "app/db/port", Value: []byte("5432"), ModifyIndex: 5},
+		},
+		"10": {
+			{Key: "app/db/host", Value: []byte("db.local"), ModifyIndex: 11},
+			{Key: "app/db/port", Value: []byte("5432"), ModifyIndex: 5},
+		},
+		//Only an unrelated key under the prefix changes.
+		"11": {
+			{Key: "app/db/host", Value: []byte("db.local"), ModifyIndex: 11},
+			{Key: "app/db/port", Value: []byte("5432"), ModifyIndex: 5},
+			{Key: "app/db/other", Value: []byte("x"), ModifyIndex: 12},
+		},
+		"12": {
+			{Key: "app/db/host", Value: []byte("db.local"), ModifyIndex: 11},
+			{Key: "app/db/port", Value: []byte("abc"), ModifyIndex: 13},
+		},
+	}
+	nextIndex := map[string]uint64{
+		"":   10,
+		"10": 11,
+		"11": 12,
+		"12": 13,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		`=~^http://127\.0\.0\.1:8500/v1/kv/app/db/`,
+		blockingResponder(responses, nextIndex, cancel),
+	)
+	client, err := api.NewClient(&api.Config{
+		HttpClient: &http.Client{},
+	})
+	if err != nil {
+		t.Error("Failed to start the client!")
+	}
+	parser := &Parser{
+		layers: kvLayers(client.KV()),
+	}
+	result := &target{}
+	var (
+		values  []target
+		indexes []uint64
+		changes [][]Change
+		errs    []error
+	)
+	err = parser.Watch(ctx, result, func(event WatchEvent) {
+		assert.Equal(t, result, event.Target)
+		values = append(values, *result)
+		indexes = append(indexes, event.Index)
+		changes = append(changes, event.Changes)
+		errs = append(errs, event.Err)
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []target{
+		{Host: "localhost", Port: 5432},
+		{Host: "db.local", Port: 5432},
+		{Host: "db.local", Port: 5432},
+	}, values)
+	assert.Equal(t, []uint64{10, 11, 13}, indexes)
+	assert.Equal(t, [][]Change{
+		nil,
+		{
+			{
+				Path: "Host",
+				Key:  "app/db/host",
+				Old:  "localhost",
+				New:  "db.local",
+			},
+		},
+		nil,
+	}, changes)
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.ErrorIs(t, errs[2], strconv.ErrSyntax)
+}
+
+func TestParser_Watch_NonPointerType(t *testing.T) {
+	client, err := api.NewClient(api.DefaultConfig())
+	if err != nil {
+		t.Error("Failed to start the client!")
+	}
+	parser := &Parser{
+		layers: kvLayers(client.KV()),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err = parser.Watch(ctx, struct{}{}, func(WatchEvent) {
+		t.Error("onChange must not be called")
+	})
+	assert.Equal(t, ErrNonPointerType, err)
+}
+
+//prefixSource records the prefixes of the blocking queries of a MemorySource.
+type prefixSource struct {
+	*MemorySource
+	mutex    sync.Mutex
+	prefixes map[string]bool
+}
+
+func (source *prefixSource) WatchList(ctx context.Context, prefix string, waitIndex uint64) (api.KVPairs, uint64, error) {
+	source.mutex.Lock()
+	source.prefixes[prefix] = true
+	source.mutex.Unlock()
+	return source.MemorySource.WatchList(ctx, prefix, waitIndex)
+}
+
+func TestParser_Watch_KeyGroups(t *testing.T) {
+	type target struct {
+		X    string `consulkv:"a/x"`
+		Y    string `consulkv:"b/y"`
+		Flag string `consulkv:"flag"`
+	}
+	source := &prefixSource{
+		MemorySource: NewMemorySource(map[string]string{
+			"a/x":  "1",
+			"b/y":  "2",
+			"flag": "on",
+		}),
+		prefixes: make(map[string]bool),
+	}
+	parser, err := NewParserWithSource(source)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var values []target
+	result := &target{}
+	err = parser.Watch(ctx, result, func(event WatchEvent) {
+		assert.NoError(t, event.Err)
+		values = append(values, *result)
+		switch len(values) {
+		case 1:
+			//Keys outside of the groups don't reload the target.
+			source.Set("zzz/unrelated", "x")
+			source.Set("b/y", "3")
+		default:
+			cancel()
+		}
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []target{
+		{X: "1", Y: "2", Flag: "on"},
+		{X: "1", Y: "3", Flag: "on"},
+	}, values)
+	//Every group is watched on its own prefix instead of the whole store.
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	assert.Equal(t, map[string]bool{"a/x": true, "b/y": true, "flag": true}, source.prefixes)
+}